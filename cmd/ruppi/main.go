@@ -5,12 +5,17 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"ruppi/internal/app"
+	"ruppi/internal/bookmarks"
 	"ruppi/internal/config"
+	"ruppi/internal/history"
 	"ruppi/internal/logger"
+	"ruppi/pkg/httpclient"
 	"ruppi/pkg/style"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	zone "github.com/lrstanley/bubblezone"
@@ -27,8 +32,45 @@ func main() {
 	kittyFlag := flag.Bool("kitty", true, "Enable Kitty terminal graphics protocol extensions.")
 	contentWidth := flag.Int("width", 80, "Content word wrap width. Default is 80.")
 	rawFlag := flag.Bool("raw", false, "Output raw content directly to stdout without TUI")
+	linksFlag := flag.String("links", "", "How links are shown: inline, or footnote for numbered references at the end of the page. Defaults to link-display in the config.")
+	restoreFlag := flag.Bool("restore", false, "Reopen the tabs of the last session.")
+	sessionFlag := flag.String("session", app.DEFAULT_SESSION, "Name of the session to save tabs to and restore from.")
+	importBookmarksFlag := flag.String("import-bookmarks", "", "Import bookmarks from a Netscape bookmarks HTML file and exit.")
+	exportBookmarksFlag := flag.String("export-bookmarks", "", "Export bookmarks to a Netscape bookmarks HTML file and exit.")
 	flag.Parse()
 
+	if *linksFlag != "" {
+		if err := config.Set("link-display", *linksFlag); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+
+	if address, ok := httpclient.NormalizeURL(*urlFlag); ok {
+		*urlFlag = address
+	}
+
+	bookmarkStore, err := bookmarks.Load(filepath.Join(config.GetDataDir(), "bookmarks.json"))
+	if err != nil {
+		log.Printf("Could not load bookmarks: %v", err)
+	}
+	historyStore, err := history.Load(filepath.Join(config.GetDataDir(), "history.json"))
+	if err != nil {
+		log.Printf("Could not load history: %v", err)
+	}
+	keymap, errs := app.NewKeymap()
+	for _, err := range errs {
+		log.Printf("Warning: %v", err)
+	}
+	httpclient.SetHelpKeys(keymap.HelpEntries)
+	app.RegisterAboutPages(bookmarkStore, historyStore)
+
+	if *importBookmarksFlag != "" || *exportBookmarksFlag != "" {
+		if err := transferBookmarks(bookmarkStore, *importBookmarksFlag, *exportBookmarksFlag); err != nil {
+			log.Fatalf("Error transferring bookmarks: %v", err)
+		}
+		return
+	}
+
 	// File output mode
 	if *outputFlag != "" {
 		if err := writeURLToFile(*urlFlag, *outputFlag, *contentWidth, *kittyFlag); err != nil {
@@ -56,8 +98,28 @@ func main() {
 	}
 
 	browserModel := NewBrowser(width, height, *contentWidth, *kittyFlag || strings.Contains(termProgram, "kitty"))
-	browserModel.Url.SetValue(*urlFlag)
-	browserModel.Tabs.NewTab(*urlFlag, browserModel.WordWrap(), browserModel.IsKitty)
+	browserModel.SessionName = *sessionFlag
+	browserModel.Keymap = keymap
+	app.RegisterTabsPage(browserModel.Tabs)
+	browserModel.Bookmarks = bookmarkStore
+	browserModel.History = historyStore
+	browserModel.Tabs.History = historyStore
+
+	restored := false
+	if *restoreFlag || config.GetSessionConfig().Restore {
+		if session, err := app.LoadSession(*sessionFlag); err == nil {
+			browserModel.Tabs.Restore(session, browserModel.WordWrap(), browserModel.IsKitty)
+			restored = true
+		} else {
+			browserModel.Logger.Add(fmt.Sprintf("Could not restore session: %v", err))
+		}
+	}
+
+	// a URL given on the command line opens in a new tab after the restored ones
+	if !restored || *urlFlag != "" {
+		browserModel.Tabs.NewTab(*urlFlag, browserModel.WordWrap(), browserModel.IsKitty)
+	}
+	browserModel.Url.SetValue(browserModel.Tabs.ActiveTab().URL())
 
 	p := tea.NewProgram(
 		browserModel,
@@ -80,6 +142,9 @@ func NewBrowser(width, height, contentWidth int, isKitty bool) app.Browser {
 	ti.Cursor.Style = style.StatusColor()
 	ti.PromptStyle = style.StatusColor()
 	ti.Cursor.TextStyle = style.StatusColor()
+	ti.CompletionStyle = style.StatusColor().Faint(true)
+	ti.ShowSuggestions = true
+	ti.KeyMap.AcceptSuggestion = key.NewBinding(key.WithKeys("right"))
 	ti.Placeholder = theme.SearchPlaceholder
 	ti.Blur()
 	ti.Prompt = theme.SearchIcon + " > "
@@ -126,3 +191,35 @@ func renderURL(url string, contentWidth int, isKitty bool) string {
 	tabs.NewTab(url, contentWidth, isKitty)
 	return tabs.Rendered()
 }
+
+// transferBookmarks imports bookmarks from importPath and exports them all to
+// exportPath; either may be empty.
+func transferBookmarks(store *bookmarks.Store, importPath, exportPath string) error {
+	if importPath != "" {
+		f, err := os.Open(importPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		n, err := store.ImportNetscape(f)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d bookmarks from: %s\n", n, importPath)
+	}
+
+	if exportPath != "" {
+		f, err := os.Create(exportPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if err := store.ExportNetscape(f); err != nil {
+			return err
+		}
+		fmt.Printf("Exported %d bookmarks to: %s\n", len(store.Bookmarks), exportPath)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+// sixelbench compares the speed and quality of the sixel encoder settings on
+// a set of images, to help pick sixel-colors, sixel-dither and sixel-workers.
+//
+//	go run ./cmd/sixelbench photo.jpg https://example.com/photo.png
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"ruppi/pkg/helper"
+	"ruppi/pkg/sixel"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type setting struct {
+	name    string
+	encoder sixel.Encoder
+}
+
+func main() {
+	width := flag.Int("width", 400, "Maximum width of the encoded image.")
+	height := flag.Int("height", 300, "Maximum height of the encoded image.")
+	runs := flag.Int("runs", 5, "Number of encodes to average the time over.")
+	flag.Parse()
+
+	inputs := flag.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"assets/demo_ss.png"}
+	}
+
+	cpus := runtime.NumCPU()
+	settings := []setting{
+		{"none/255", sixel.Encoder{Colors: 255}},
+		{fmt.Sprintf("none/255 x%d", cpus), sixel.Encoder{Colors: 255, Workers: cpus}},
+		{fmt.Sprintf("ordered/255 x%d", cpus), sixel.Encoder{Colors: 255, Ordered: true, Workers: cpus}},
+		{"floyd-steinberg/255", sixel.Encoder{Colors: 255, Dither: true}},
+		{fmt.Sprintf("ordered/64 x%d", cpus), sixel.Encoder{Colors: 64, Ordered: true, Workers: cpus}},
+		{"floyd-steinberg/64", sixel.Encoder{Colors: 64, Dither: true}},
+		{fmt.Sprintf("ordered/16 x%d", cpus), sixel.Encoder{Colors: 16, Ordered: true, Workers: cpus}},
+		{"floyd-steinberg/16", sixel.Encoder{Colors: 16, Dither: true}},
+	}
+
+	for _, input := range inputs {
+		img, err := loadImage(input)
+		if err != nil {
+			log.Printf("skipping %s: %v", input, err)
+			continue
+		}
+		reference := helper.ResizeImage(img, *width, *height)
+
+		fmt.Printf("%s (%dx%d)\n", input, reference.Bounds().Dx(), reference.Bounds().Dy())
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, "setting\ttime\tsize\tPSNR\t")
+
+		for _, s := range settings {
+			var out string
+			start := time.Now()
+			for range *runs {
+				enc := s.encoder
+				enc.Width, enc.Height = *width, *height
+				if out, err = enc.EncodeToString(img); err != nil {
+					break
+				}
+			}
+			if err != nil {
+				fmt.Fprintf(w, "%s\terror: %v\t\t\t\n", s.name, err)
+				continue
+			}
+			elapsed := time.Since(start) / time.Duration(*runs)
+
+			fmt.Fprintf(w, "%s\t%v\t%dKB\t%s\t\n", s.name, elapsed.Round(time.Microsecond), len(out)/1024, psnr(reference, out))
+		}
+		w.Flush()
+		fmt.Println()
+	}
+}
+
+func loadImage(input string) (image.Image, error) {
+	if strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://") {
+		return helper.ImageFromURL(input)
+	}
+
+	f, err := os.Open(input)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	anim, err := helper.DecodeAnimation(f)
+	if err != nil {
+		return nil, err
+	}
+	return anim.Frames[0], nil
+}
+
+// psnr decodes the sixel data and returns its peak signal-to-noise ratio
+// against the reference image. Higher is better; above ~35dB differences are
+// hard to see.
+func psnr(reference image.Image, data string) string {
+	var decoded image.Image
+	if err := sixel.NewDecoder(strings.NewReader(data)).Decode(&decoded); err != nil {
+		return "n/a"
+	}
+
+	bounds := reference.Bounds()
+	var sum float64
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			r1, g1, b1, _ := reference.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			r2, g2, b2, _ := decoded.At(x, y).RGBA()
+			for _, d := range []float64{
+				float64(r1>>8) - float64(r2>>8),
+				float64(g1>>8) - float64(g2>>8),
+				float64(b1>>8) - float64(b2>>8),
+			} {
+				sum += d * d
+			}
+		}
+	}
+
+	mse := sum / float64(bounds.Dx()*bounds.Dy()*3)
+	if mse == 0 {
+		return "inf"
+	}
+	return fmt.Sprintf("%.1fdB", 10*math.Log10(255*255/mse))
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"ruppi/internal/bookmarks"
+	"ruppi/internal/config"
+	"ruppi/internal/history"
+	"ruppi/pkg/httpclient"
+	"strings"
+)
+
+// RegisterAboutPages sets up the about: pages that show the browser's own
+// data.
+func RegisterAboutPages(bookmarkStore *bookmarks.Store, historyStore *history.Store) {
+	httpclient.RegisterAboutPage("bookmarks", func(u *url.URL) (string, error) {
+		return bookmarksPage(bookmarkStore, u.Query().Get("q")), nil
+	})
+	httpclient.RegisterAboutPage("history", func(u *url.URL) (string, error) {
+		return historyPage(historyStore, u.Query().Get("q")), nil
+	})
+}
+
+// RegisterTabsPage sets up about:tabs, listing the tabs open in ts.
+func RegisterTabsPage(ts *Tabs) {
+	httpclient.RegisterAboutPage("tabs", func(*url.URL) (string, error) {
+		return tabsPage(ts), nil
+	})
+}
+
+// tabsPage lists the open tabs with how far back and forward they can go.
+func tabsPage(ts *Tabs) string {
+	var sb strings.Builder
+	sb.WriteString("<title>Tabs</title>\n<h1>Tabs</h1>\n")
+	fmt.Fprintf(&sb, "<p>%d tabs open.</p>\n<ol>\n", len(ts.Tabs))
+
+	for _, tab := range ts.Tabs {
+		url := tab.url
+		if url == "" {
+			url = "about:newtab"
+		}
+		title := tab.title
+		if title == "" {
+			title = url
+		}
+
+		fmt.Fprintf(&sb, `<li><a href="%s">%s</a>`, html.EscapeString(url), html.EscapeString(title))
+		if tab == ts.activeTab {
+			sb.WriteString(" <i>active</i>")
+		}
+		if len(tab.back) > 0 || len(tab.forward) > 0 {
+			fmt.Fprintf(&sb, " <code>%d back, %d forward</code>", len(tab.back), len(tab.forward))
+		}
+		sb.WriteString("</li>\n")
+	}
+	sb.WriteString("</ol>\n")
+	return sb.String()
+}
+
+// bookmarksPage lists the bookmarks grouped by folder, or the ones matching
+// query when there is one.
+func bookmarksPage(store *bookmarks.Store, query string) string {
+	var sb strings.Builder
+	sb.WriteString("<title>Bookmarks</title>\n<h1>Bookmarks</h1>\n")
+
+	if query != "" {
+		results := store.Search(query)
+		fmt.Fprintf(&sb, "<p>%d bookmarks matching <code>%s</code></p>\n", len(results), html.EscapeString(query))
+		writeBookmarkList(&sb, results, true)
+		return sb.String()
+	}
+
+	if len(store.Bookmarks) == 0 {
+		sb.WriteString("<p>No bookmarks yet. Bookmark a page with <code>" + html.EscapeString(config.GetTheme().BookmarkKey) + "</code>.</p>\n")
+		return sb.String()
+	}
+
+	sb.WriteString("<p>Search them from the URL bar with <code>*query</code>.</p>\n")
+	for _, folder := range store.Folders() {
+		if folder != "" {
+			fmt.Fprintf(&sb, "<h2>%s</h2>\n", html.EscapeString(folder))
+		}
+		writeBookmarkList(&sb, store.InFolder(folder), false)
+	}
+	return sb.String()
+}
+
+func writeBookmarkList(sb *strings.Builder, list []bookmarks.Bookmark, showFolder bool) {
+	sb.WriteString("<ul>\n")
+	for _, b := range list {
+		title := b.Title
+		if title == "" {
+			title = b.URL
+		}
+		fmt.Fprintf(sb, `<li><a href="%s">%s</a>`, html.EscapeString(b.URL), html.EscapeString(title))
+		if showFolder && b.Folder != "" {
+			fmt.Fprintf(sb, " <i>%s</i>", html.EscapeString(b.Folder))
+		}
+		for _, tag := range b.Tags {
+			fmt.Fprintf(sb, " <code>#%s</code>", html.EscapeString(tag))
+		}
+		sb.WriteString("</li>\n")
+	}
+	sb.WriteString("</ul>\n")
+}
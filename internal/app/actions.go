@@ -0,0 +1,220 @@
+package app
+
+import (
+	"ruppi/internal/config"
+	"ruppi/pkg/style"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// horizontalScrollStep is how many columns scroll-left and scroll-right move.
+const horizontalScrollStep = 6
+
+// keymap returns the browser's keymap, building the default one if none was
+// given.
+func (b *Browser) keymap() *Keymap {
+	if b.Keymap == nil {
+		b.Keymap, _ = NewKeymap()
+	}
+	return b.Keymap
+}
+
+// runAction does what a key binding asked for, count times where repeating
+// makes sense. A count of 0 is no count, which does it once.
+func (b *Browser) runAction(action Action, count int) tea.Cmd {
+	repeat := max(count, 1)
+	switch action {
+	case ACTION_QUIT:
+		return b.quit()
+	case ACTION_FOCUS_URL:
+		b.ActivePane = ACTIVE_INPUT_URL
+		return b.Url.Focus()
+	case ACTION_TOGGLE_INSPECTOR:
+		b.IsInspectorOpen = !b.IsInspectorOpen
+		return toggleInspectorWindow(b.IsInspectorOpen)
+	case ACTION_IMAGE_VIEWER:
+		b.showImageHints()
+	case ACTION_BACK:
+		return b.goHistory(-repeat)
+	case ACTION_FORWARD:
+		return b.goHistory(repeat)
+	case ACTION_BOOKMARK:
+		return b.bookmarkActiveTab()
+	case ACTION_NEW_TAB:
+		return createNewTabCmd("")
+	case ACTION_NEXT_TAB:
+		return b.switchTab(repeat)
+	case ACTION_PREV_TAB:
+		return b.switchTab(-repeat)
+	case ACTION_HELP:
+		b.showHelp = !b.showHelp
+	case ACTION_YANK_URL:
+		return b.yankURL()
+	case ACTION_COMMAND_LINE:
+		return b.openCommandLine()
+	case ACTION_COMMAND_PALETTE:
+		return b.openPalette()
+	case ACTION_FIND:
+		return b.openFind()
+	case ACTION_FIND_NEXT:
+		b.findNext(repeat)
+	case ACTION_FIND_PREV:
+		b.findNext(-repeat)
+	case ACTION_OUTLINE:
+		return b.toggleOutline()
+	case ACTION_READER:
+		b.toggleReader()
+	case ACTION_DETAILS:
+		b.showDetailsHints()
+	default:
+		b.scroll(action, count)
+	}
+	return nil
+}
+
+// goHistory moves through the active tab's history, back for a negative
+// delta and forward for a positive one.
+func (b *Browser) goHistory(delta int) tea.Cmd {
+	tab := b.Tabs.ActiveTab()
+	moved := false
+	for ; delta < 0; delta++ {
+		moved = tab.Back(b.WordWrap(), b.IsKitty) || moved
+	}
+	for ; delta > 0; delta-- {
+		moved = tab.Forward(b.WordWrap(), b.IsKitty) || moved
+	}
+
+	if moved {
+		return b.showActiveTab()
+	}
+	return nil
+}
+
+// yankURL copies the active tab's URL to the clipboard.
+func (b *Browser) yankURL() tea.Cmd {
+	url := b.Tabs.ActiveTab().URL()
+	if url == "" {
+		return nil
+	}
+
+	b.showMessage("Copied " + url)
+	return copyToClipboardCmd(url)
+}
+
+// toggleReader shows the active tab's page in reader mode, or the whole page
+// again.
+func (b *Browser) toggleReader() {
+	tab := b.Tabs.ActiveTab()
+	tab.setReader(!tab.reader)
+	tab.readerChosen = tab.reader
+	if !tab.reader && tab.article == nil {
+		b.showMessage("No article found on this page")
+		return
+	}
+
+	b.rerender()
+	tab.setScrollPos(0)
+	b.Viewport.GotoTop()
+	if tab.reader {
+		b.showMessage("Reader mode on")
+	} else {
+		b.showMessage("Reader mode off")
+	}
+}
+
+// toggleDetails opens or closes the <details> at index i of the active tab.
+func (b *Browser) toggleDetails(i int) {
+	b.Tabs.ActiveTab().details[i].details.Toggle()
+	b.rerender()
+}
+
+// rerender renders the active tab's page again after it changed, keeping
+// where it is scrolled to.
+func (b *Browser) rerender() {
+	tab := b.Tabs.ActiveTab()
+	tab.Render(b.WordWrap(), b.IsKitty)
+	if b.find.tab == tab {
+		// the matches were of the page as it was
+		b.find.tab = nil
+	}
+	b.Viewport.SetContent(b.pageContent())
+}
+
+// scroll moves the page, or the inspector while it is open, count times. As
+// in vim, going to the top or bottom with a count goes to that line instead.
+func (b *Browser) scroll(action Action, count int) {
+	vp := &b.Viewport
+	if b.IsInspectorOpen {
+		vp = &b.InspectorViewport
+	}
+
+	if count > 0 && (action == ACTION_TOP || action == ACTION_BOTTOM) {
+		vp.SetYOffset(count - 1)
+		return
+	}
+
+	for range max(count, 1) {
+		switch action {
+		case ACTION_SCROLL_DOWN:
+			vp.ScrollDown(1)
+		case ACTION_SCROLL_UP:
+			vp.ScrollUp(1)
+		case ACTION_SCROLL_LEFT:
+			vp.ScrollLeft(horizontalScrollStep)
+		case ACTION_SCROLL_RIGHT:
+			vp.ScrollRight(horizontalScrollStep)
+		case ACTION_HALF_PAGE_DOWN:
+			vp.HalfPageDown()
+		case ACTION_HALF_PAGE_UP:
+			vp.HalfPageUp()
+		case ACTION_PAGE_DOWN:
+			vp.PageDown()
+		case ACTION_PAGE_UP:
+			vp.PageUp()
+		case ACTION_TOP:
+			vp.GotoTop()
+		case ACTION_BOTTOM:
+			vp.GotoBottom()
+		}
+	}
+}
+
+// switchTab activates the tab delta places away from the active one,
+// wrapping around at either end.
+func (b *Browser) switchTab(delta int) tea.Cmd {
+	n := len(b.Tabs.Tabs)
+	if n < 2 {
+		return nil
+	}
+
+	index := ((b.Tabs.activeTabID+delta)%n + n) % n
+	b.Tabs.SwitchTo(index, b.WordWrap(), b.IsKitty)
+	b.Viewport.SetContent(b.pageContent())
+	b.Viewport.SetYOffset(b.Tabs.ActiveTab().scrollPos)
+	return updateURLCmd(b.Tabs.ActiveTab().url)
+}
+
+// helpView draws the key bindings of the active keymap as a box of columns.
+func (b *Browser) helpView(width int) []string {
+	theme := config.GetTheme()
+
+	h := help.New()
+	h.FullSeparator = "   "
+	h.Styles.FullKey = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.TabActiveColor)).Bold(true)
+	h.Styles.FullDesc = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.BrowserForeground))
+	h.Styles.FullSeparator = lipgloss.NewStyle()
+	h.Width = width - 4
+
+	box := style.OmniboxStyle().
+		Width(width-2).
+		Padding(0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(theme.TabActiveColor)).
+		BorderBackground(lipgloss.Color(theme.StatusBarColor))
+
+	title := style.TitleStyle.Render("Keys") + "  any key closes this help\n\n"
+	return strings.Split(box.Render(title+h.FullHelpView(b.keymap().HelpGroups())), "\n")
+}
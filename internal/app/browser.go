@@ -2,17 +2,24 @@ package app
 
 import (
 	"fmt"
+	"net/url"
+	"ruppi/internal/bookmarks"
 	"ruppi/internal/config"
+	"ruppi/internal/dom"
+	"ruppi/internal/history"
 	"ruppi/internal/logger"
 	"ruppi/pkg/httpclient"
+	"ruppi/pkg/kitty"
 	"ruppi/pkg/style"
 	"strconv"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	zone "github.com/lrstanley/bubblezone"
+	"github.com/muesli/reflow/truncate"
 )
 
 type active_session int
@@ -23,6 +30,22 @@ const (
 
 	ACTIVE_VIEWPORT active_session = iota
 	ACTIVE_INPUT_URL
+	ACTIVE_IMAGE_HINTS
+	ACTIVE_DETAILS_HINTS
+	ACTIVE_IMAGE_VIEWER
+	ACTIVE_OUTLINE
+	// the prompts that take over the URL bar
+	ACTIVE_BOOKMARK_PROMPT
+	ACTIVE_COMMAND_LINE
+	ACTIVE_PALETTE
+	ACTIVE_FIND
+
+	// maxHints is how many images or details can be picked with a single
+	// key.
+	maxHints = 9
+
+	// PAGE_ID marks the page in the view, to tell which line was clicked.
+	PAGE_ID = "ruppi_page"
 )
 
 type Browser struct {
@@ -42,10 +65,46 @@ type Browser struct {
 	ActivePane active_session
 
 	Logger *logger.Logger
+
+	// SessionName is the session the open tabs are saved to.
+	SessionName string
+
+	Bookmarks *bookmarks.Store
+	History   *history.Store
+
+	// bookmarkURL is the URL whose folder and tags are being typed in the
+	// bookmark prompt.
+	bookmarkURL string
+
+	// findOrigin is where the page was scrolled to when the find prompt
+	// was opened.
+	findOrigin int
+	find       findState
+	// message is shown in place of the URL until the next key press.
+	message string
+
+	// completions maps the history completions offered in the URL bar to
+	// the URLs they stand for.
+	completions map[string]string
+	omnibox     omnibox
+
+	// Keymap maps keys to actions while browsing the page.
+	Keymap   *Keymap
+	showHelp bool
+
+	// showOutline is set while the outline of the page is shown next to it.
+	showOutline     bool
+	outlineSelected int
+
+	isAnimating bool
+	imageHints  []*dom.InlineImage
+	// detailsHints are the indexes of the labelled <details> of the tab
+	detailsHints []int
+	viewer       *imageViewer
 }
 
 func (b Browser) Init() tea.Cmd {
-	return b.Logger.Listen()
+	return tea.Batch(b.Logger.Listen(), sessionSaveCmd(config.GetSessionConfig().SaveInterval))
 }
 
 func (b Browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -53,11 +112,102 @@ func (b Browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmd  tea.Cmd
 		cmds []tea.Cmd
 	)
+	// whatever the message did to the page, the tab keeps where it is
+	defer b.recordScrollPos()
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		if msg.String() == "ctrl+c" {
-			return b, tea.Quit
+			return b, b.quit()
+		}
+		b.message = ""
+
+		if b.ActivePane == ACTIVE_IMAGE_VIEWER {
+			if b.viewer.Update(msg) {
+				b.closeImageViewer()
+			} else {
+				b.viewer.render(b.Width, b.Height, b.IsKitty)
+			}
+			return b, nil
+		}
+
+		if b.ActivePane == ACTIVE_IMAGE_HINTS {
+			b.selectImageHint(msg.String())
+			return b, nil
+		}
+
+		if b.ActivePane == ACTIVE_DETAILS_HINTS {
+			b.selectDetailsHint(msg.String())
+			return b, nil
+		}
+
+		if b.ActivePane == ACTIVE_BOOKMARK_PROMPT {
+			switch msg.String() {
+			case "enter":
+				b.labelBookmark()
+			case "esc":
+				b.endPrompt()
+			default:
+				b.Url, cmd = b.Url.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+			return b, tea.Batch(cmds...)
+		}
+
+		if b.ActivePane == ACTIVE_COMMAND_LINE {
+			switch msg.String() {
+			case "enter":
+				line := b.Url.Value()
+				b.endPrompt()
+				cmds = append(cmds, b.runCommand(line))
+			case "esc":
+				b.endPrompt()
+			default:
+				b.Url, cmd = b.Url.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+			return b, tea.Batch(cmds...)
+		}
+
+		if b.ActivePane == ACTIVE_FIND {
+			switch msg.String() {
+			case "enter":
+				b.endPrompt()
+				if len(b.find.matches) == 0 && b.find.query != "" {
+					b.showMessage(fmt.Sprintf("Not found: %s", b.find.query))
+				}
+			case "esc":
+				b.cancelFind()
+			default:
+				value := b.Url.Value()
+				b.Url, cmd = b.Url.Update(msg)
+				if b.Url.Value() != value {
+					b.findInPage(b.Url.Value(), b.findOrigin)
+				}
+				cmds = append(cmds, cmd)
+			}
+			return b, tea.Batch(cmds...)
+		}
+
+		if b.ActivePane == ACTIVE_PALETTE {
+			switch msg.String() {
+			case "enter":
+				cmds = append(cmds, b.runPaletteItem())
+			case "esc":
+				b.endPrompt()
+			case "down", "ctrl+n", "tab":
+				b.moveOmnibox(1)
+			case "up", "ctrl+p", "shift+tab":
+				b.moveOmnibox(-1)
+			default:
+				value := b.Url.Value()
+				b.Url, cmd = b.Url.Update(msg)
+				if b.Url.Value() != value {
+					b.updatePalette()
+				}
+				cmds = append(cmds, cmd)
+			}
+			return b, tea.Batch(cmds...)
 		}
 
 		if b.Url.Focused() {
@@ -66,25 +216,37 @@ func (b Browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				cmds = append(cmds, b.submitURL())
 			case "esc":
 				b.Url.Blur()
+				b.closeOmnibox()
 				b.ActivePane = ACTIVE_VIEWPORT
+			case "down", "ctrl+n", "tab":
+				b.moveOmnibox(1)
+			case "up", "ctrl+p", "shift+tab":
+				b.moveOmnibox(-1)
 			default:
+				value := b.Url.Value()
 				b.Url, cmd = b.Url.Update(msg)
+				if b.Url.Value() != value {
+					b.updateCompletions()
+					b.updateOmnibox()
+				}
 				cmds = append(cmds, cmd)
 			}
 			return b, tea.Batch(cmds...)
 		}
 
-		theme := config.GetTheme()
-		switch msg.String() {
-		case theme.QuitKey:
-			return b, tea.Quit
-		case theme.SearchKey, "/":
-			b.ActivePane = ACTIVE_INPUT_URL
-			return b, b.Url.Focus()
-		case theme.InspectorToggleKey:
-			b.IsInspectorOpen = !b.IsInspectorOpen
+		if b.ActivePane == ACTIVE_OUTLINE {
+			cmds = append(cmds, b.updateOutline(msg.String()))
+			return b, tea.Batch(cmds...)
+		}
+
+		if b.showHelp {
+			// any key closes the help
+			b.showHelp = false
+			return b, nil
+		}
 
-			return b, toggleInspectorWindow(b.IsInspectorOpen)
+		if action, count, ok := b.keymap().Press(msg.String()); ok {
+			cmds = append(cmds, b.runAction(action, count))
 		}
 
 	case refreshViewport:
@@ -95,13 +257,17 @@ func (b Browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		if !b.Ready {
-			b.Viewport = viewport.New(b.Width, viewportHeight)
+			b.Viewport = viewport.New(b.pageWidth(), viewportHeight)
 			b.InspectorViewport = viewport.New(b.Width, inspectorBufferSize)
-			b.Viewport.SetContent(b.Tabs.Rendered())
+			// keys reach the viewports through the keymap instead
+			b.Viewport.KeyMap = viewport.KeyMap{}
+			b.InspectorViewport.KeyMap = viewport.KeyMap{}
+			b.Viewport.SetContent(b.pageContent())
+			b.Viewport.SetYOffset(b.Tabs.ActiveTab().scrollPos)
 			b.InspectorViewport.SetContent(b.Logger.Get())
 			b.Ready = true
 		} else {
-			b.Viewport.Width = b.Width
+			b.Viewport.Width = b.pageWidth()
 			b.Viewport.Height = viewportHeight
 			b.InspectorViewport.Width = b.Width
 			b.InspectorViewport.Height = inspectorBufferSize
@@ -117,14 +283,19 @@ func (b Browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case newTabMsg:
 		b.Tabs.NewTab(string(msg), b.WordWrap(), b.IsKitty)
 		cmds = append(cmds, updateURLCmd(b.Tabs.ActiveTab().url))
-		b.Viewport.SetContent(b.Tabs.Rendered())
-		b.Viewport.GotoTop()
+		b.Viewport.SetContent(b.pageContent())
+		b.Viewport.SetYOffset(b.Tabs.ActiveTab().scrollPos)
+	case navigateMsg:
+		b.Tabs.ChangeActiveTabURL(string(msg), b.WordWrap(), b.IsKitty)
+		cmds = append(cmds, b.showActiveTab())
+	case actionMsg:
+		cmds = append(cmds, b.runAction(msg.action, msg.count))
 	case changeTabMsg:
 		b.Logger.Add(strconv.Itoa(int(msg)))
 		b.Logger.Add(strconv.Itoa(b.Tabs.visibleTabStartIndex))
-		b.Tabs.ChangeTab(int(msg))
+		b.Tabs.ChangeTab(int(msg), b.WordWrap(), b.IsKitty)
 		cmds = append(cmds, updateURLCmd(b.Tabs.ActiveTab().url))
-		b.Viewport.SetContent(b.Tabs.Rendered())
+		b.Viewport.SetContent(b.pageContent())
 		b.Viewport.GotoTop()
 		cmds = append(cmds, updateScrollPositionCmd(b.Tabs.activeTab.scrollPos))
 
@@ -147,12 +318,47 @@ func (b Browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				b.Tabs.MoveRight()
 			}
 
-			if zone.Get("url_input_bar").InBounds(msg) {
+			if zone.Get("url_input_bar").InBounds(msg) && !b.inPrompt() {
 				b.Logger.Add("URL input bar clicked")
 				b.ActivePane = ACTIVE_INPUT_URL
 				cmds = append(cmds, b.Url.Focus())
 			}
 
+			for i := range b.omnibox.items {
+				if zone.Get(fmt.Sprintf("%s%d", OMNIBOX_ID, i)).InBounds(msg) {
+					b.omnibox.selected = i
+					if b.ActivePane == ACTIVE_PALETTE {
+						cmds = append(cmds, b.runPaletteItem())
+					} else {
+						cmds = append(cmds, b.submitURL())
+					}
+					break
+				}
+			}
+
+			for i := range b.Tabs.ActiveTab().outline {
+				if b.showOutline && zone.Get(fmt.Sprintf("%s%d", OUTLINE_ID, i)).InBounds(msg) {
+					b.jumpToSection(i)
+					break
+				}
+			}
+
+			// the start of the page's zone can land on the row above it, so
+			// its rows are counted back from where it ends
+			if page := zone.Get(PAGE_ID); b.ActivePane == ACTIVE_VIEWPORT && !b.showHelp && !page.IsZero() {
+				top := page.EndY - b.Viewport.Height + 1
+				if i := b.Tabs.ActiveTab().detailsAt(b.Viewport.YOffset + msg.Y - top); msg.Y >= top && i >= 0 {
+					b.toggleDetails(i)
+				}
+			}
+
+			for i, img := range b.imageHints {
+				if zone.Get(fmt.Sprintf("%s%d", IMAGE_HINT_ID, i)).InBounds(msg) {
+					b.openImageViewer(img)
+					break
+				}
+			}
+
 			for i := 0; i <= b.Tabs.TotalTabCount; i++ {
 				if zone.Get(fmt.Sprintf("%s%d", TAB_ID, i)).InBounds(msg) {
 					b.Logger.Add(fmt.Sprintf("%s%d", TAB_ID, i))
@@ -162,6 +368,25 @@ func (b Browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+	case sessionSaveMsg:
+		b.saveSession()
+		b.saveHistory()
+		cmds = append(cmds, sessionSaveCmd(config.GetSessionConfig().SaveInterval))
+
+	case animationTickMsg:
+		b.isAnimating = false
+		switch b.ActivePane {
+		case ACTIVE_IMAGE_VIEWER:
+			if b.viewer.image.Advance(animationInterval) {
+				b.viewer.render(b.Width, b.Height, b.IsKitty)
+			}
+		case ACTIVE_IMAGE_HINTS, ACTIVE_DETAILS_HINTS:
+		default:
+			if b.Tabs.ActiveTab().Animate(animationInterval, b.Viewport.YOffset, b.Viewport.Height) {
+				b.Viewport.SetContent(b.pageContent())
+			}
+		}
+
 	case logger.LogMsg:
 		b.InspectorViewport.SetContent(string(msg))
 		b.InspectorViewport.ScrollDown(1)
@@ -173,23 +398,22 @@ func (b Browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		b.Height = msg.Height
 
 		b.Tabs.Render(b.WordWrap(), b.IsKitty)
+		if b.ActivePane == ACTIVE_IMAGE_VIEWER {
+			b.viewer.render(b.Width, b.Height, b.IsKitty)
+		}
 		cmds = append(cmds, toggleInspectorWindow(b.IsInspectorOpen))
 	}
 
-	if b.ActivePane == ACTIVE_VIEWPORT && !b.IsInspectorOpen {
+	if (b.ActivePane == ACTIVE_VIEWPORT || b.ActivePane == ACTIVE_OUTLINE) && !b.IsInspectorOpen {
 		b.Viewport, cmd = b.Viewport.Update(msg)
-		if !b.Viewport.AtTop() {
-			b.Tabs.SetScrollPos(int(b.Viewport.ScrollPercent() * float64(b.Viewport.TotalLineCount())))
-		}
 		cmds = append(cmds, cmd)
 	} else if b.ActivePane == ACTIVE_VIEWPORT && b.IsInspectorOpen {
 		b.InspectorViewport, cmd = b.InspectorViewport.Update(msg)
-		if !b.InspectorViewport.AtTop() {
-			b.Tabs.SetScrollPos(int(b.InspectorViewport.ScrollPercent() * float64(b.InspectorViewport.TotalLineCount())))
-		}
 		cmds = append(cmds, cmd)
 	}
 
+	cmds = append(cmds, b.startAnimation())
+
 	return b, tea.Batch(cmds...)
 }
 
@@ -198,43 +422,368 @@ func (b Browser) View() string {
 		return "\n  Initializing..."
 	}
 
+	if b.ActivePane == ACTIVE_IMAGE_VIEWER {
+		viewer := b.viewer.View(b.Width, b.Height)
+		if b.IsKitty {
+			viewer = kitty.ClearPlacements + viewer
+		}
+		return viewer
+	}
+
 	inspectorWindow := ""
 	if b.IsInspectorOpen {
 		inspectorWindow = style.InspectorStyle().Width(b.Width-2).Border(lipgloss.NormalBorder(), true, false, false).Render(b.InspectorViewport.View())
 	}
 
-	theme := config.GetTheme()
-	statusBar := style.StatusStyle().Width(b.Width - 2).Render(fmt.Sprintf("%s%s%s%s", style.LogoStyle().Render("Ruppi 🐦"), zone.Mark("url_input_bar", style.StatusColor().PaddingLeft(1).Render(b.Url.View())), style.StatusColor().PaddingRight(1).Render(fmt.Sprintf("%3.f%%", b.Viewport.ScrollPercent()*100)), style.LogoStyle().Render(theme.InspectorToggleKey)))
+	position := fmt.Sprintf("%3.f%%", b.Viewport.ScrollPercent()*100)
+	if b.Tabs.ActiveTab().reader {
+		position = "reader " + position
+	}
+	if found := b.findStatus(); found != "" {
+		position = found + " " + position
+	}
+	if pending := b.keymap().Pending(); pending != "" {
+		position = pending + " " + position
+	}
+	// the URL bar gives up the room taken by anything shown before the percentage
+	b.Url.Width -= max(lipgloss.Width(position)-4, 0)
+	inspectorKey := b.keymap().Binding(ACTION_TOGGLE_INSPECTOR).Help().Key
+	urlBar := b.Url.View()
+	if b.message != "" && !b.Url.Focused() {
+		width := lipgloss.Width(urlBar)
+		urlBar = lipgloss.NewStyle().Width(width).Render(truncate.StringWithTail(b.message, uint(width), "..."))
+	}
+	statusBar := style.StatusStyle().Width(b.Width - 2).Render(fmt.Sprintf("%s%s%s%s", style.LogoStyle().Render("Ruppi 🐦"), zone.Mark("url_input_bar", style.StatusColor().PaddingLeft(1).Render(urlBar)), style.StatusColor().PaddingRight(1).Render(position), style.LogoStyle().Render(inspectorKey)))
 	tabs := lipgloss.NewStyle().MarginBottom(1).Render(b.Tabs.ShowTabs(b.Width - 2))
-	body := fmt.Sprintf("%s%s%s%s", tabs, statusBar, b.Viewport.View(), inspectorWindow)
+
+	// Kitty placements stay on screen until deleted, so clear them whenever
+	// the page is redrawn and let the visible image lines place them again.
+	viewportView := zone.Mark(PAGE_ID, b.Viewport.View())
+	if b.showOutline {
+		viewportView = lipgloss.JoinHorizontal(lipgloss.Top, viewportView, b.outlineView(b.Viewport.Height))
+	}
+	if b.Url.Focused() && len(b.omnibox.items) > 0 {
+		viewportView = overlay(viewportView, b.omniboxView(b.Width-2))
+	} else if b.showHelp {
+		viewportView = overlay(viewportView, b.helpView(b.Width-2))
+	}
+	if b.IsKitty && config.GetSixelConfig().Enabled {
+		viewportView = kitty.ClearPlacements + viewportView
+	}
+
+	body := fmt.Sprintf("%s%s%s%s", tabs, statusBar, viewportView, inspectorWindow)
 	return zone.Scan(lipgloss.Place(b.Width, b.Height, lipgloss.Left, lipgloss.Top, style.AppStyle().Width(b.Width).Render(body)))
 }
 
+// overlay draws lines over the top of view, covering the page rather than
+// pushing it down.
+func overlay(view string, lines []string) string {
+	viewLines := strings.Split(view, "\n")
+	copy(viewLines, lines[:min(len(lines), len(viewLines))])
+	return strings.Join(viewLines, "\n")
+}
+
 func (b *Browser) WordWrap() int {
 	contentWidth := b.ContentWidth
 	if contentWidth > 120 {
 		contentWidth = 120
 	}
-	if contentWidth > b.Width {
-		contentWidth = b.Width - 2
-	}
-	return contentWidth
+	// the page is never wider than the viewport, which is narrower next
+	// to the outline
+	return min(contentWidth, b.pageWidth()-1)
 }
 
 func (b *Browser) submitURL() tea.Cmd {
+	if selected := b.selectedOmniboxItem(); selected != nil && selected.fill != "" {
+		b.Url.SetValue(selected.fill)
+		b.Url.CursorEnd()
+		b.updateOmnibox()
+		return nil
+	}
+
 	b.Url.Blur()
 	b.ActivePane = ACTIVE_VIEWPORT
-	url := b.Url.Value()
+	selected := b.selectedOmniboxItem()
+	finalURL := b.resolveURL(b.Url.Value())
+	if selected != nil {
+		finalURL = selected.url
+	}
+	b.closeOmnibox()
 
-	var finalURL string
-	if !httpclient.IsURL(url) {
-		finalURL = httpclient.SearchURL(url)
-	} else {
-		finalURL = url
+	if selected != nil && selected.tab >= 0 {
+		b.Tabs.SwitchTo(selected.tab, b.WordWrap(), b.IsKitty)
+		b.Viewport.SetContent(b.pageContent())
+		b.Viewport.SetYOffset(b.Tabs.ActiveTab().scrollPos)
+		return updateURLCmd(finalURL)
 	}
 
 	b.Tabs.ChangeActiveTabURL(finalURL, b.WordWrap(), b.IsKitty)
-	b.Viewport.SetContent(b.Tabs.Rendered())
-	b.Viewport.GotoTop()
+	b.Viewport.SetContent(b.pageContent())
+	b.Viewport.SetYOffset(b.Tabs.ActiveTab().scrollPos)
 	return updateURLCmd(finalURL)
 }
+
+// updateCompletions offers the visited URLs that start with what has been
+// typed in the URL bar, best frecency first, as inline text accepted with the
+// right arrow.
+func (b *Browser) updateCompletions() {
+	b.completions = map[string]string{}
+	if b.History == nil {
+		return
+	}
+
+	var suggestions []string
+	for _, c := range b.History.Complete(b.Url.Value(), maxCompletions) {
+		b.completions[c.Text] = c.URL
+		suggestions = append(suggestions, c.Text)
+	}
+	b.Url.SetSuggestions(suggestions)
+}
+
+// bookmarkSearchURL returns the best bookmark matching query, or the
+// bookmarks page filtered by it when nothing matches.
+func (b *Browser) bookmarkSearchURL(query string) string {
+	if b.Bookmarks != nil && query != "" {
+		if results := b.Bookmarks.Search(query); len(results) > 0 {
+			return results[0].URL
+		}
+	}
+	return "about:bookmarks?q=" + url.QueryEscape(query)
+}
+
+// bookmarkActiveTab bookmarks the page in the active tab and asks for a
+// folder and tags for it in the URL bar.
+func (b *Browser) bookmarkActiveTab() tea.Cmd {
+	tab := b.Tabs.ActiveTab()
+	if b.Bookmarks == nil || tab.URL() == "" || httpclient.IsAboutURL(tab.URL()) {
+		return nil
+	}
+
+	bookmark, exists := b.Bookmarks.Get(tab.URL())
+	if !exists {
+		bookmark = bookmarks.Bookmark{URL: tab.URL(), Title: tab.Title()}
+		if err := b.Bookmarks.Add(bookmark); err != nil {
+			b.Logger.Add(fmt.Sprintf("Could not save bookmark: %v", err))
+			return nil
+		}
+		b.Logger.Add(fmt.Sprintf("Bookmarked %s", tab.URL()))
+	}
+
+	labels := bookmark.Folder
+	for _, tag := range bookmark.Tags {
+		labels += " #" + tag
+	}
+
+	b.bookmarkURL = tab.URL()
+	b.closeOmnibox()
+	b.ActivePane = ACTIVE_BOOKMARK_PROMPT
+	b.Url.Prompt = "★ > "
+	b.Url.Placeholder = "folder #tags"
+	b.Url.SetValue(strings.TrimSpace(labels))
+	return b.Url.Focus()
+}
+
+// labelBookmark sets the folder and tags typed in the URL bar on the bookmark
+// being added.
+func (b *Browser) labelBookmark() {
+	bookmark, ok := b.Bookmarks.Get(b.bookmarkURL)
+	if ok {
+		bookmark.Folder, bookmark.Tags = bookmarks.ParseLabels(b.Url.Value())
+		if err := b.Bookmarks.Add(bookmark); err != nil {
+			b.Logger.Add(fmt.Sprintf("Could not save bookmark: %v", err))
+		}
+	}
+	b.endPrompt()
+}
+
+// inPrompt reports whether the URL bar is one of the prompts rather than the
+// URL of the active tab.
+func (b *Browser) inPrompt() bool {
+	switch b.ActivePane {
+	case ACTIVE_BOOKMARK_PROMPT, ACTIVE_COMMAND_LINE, ACTIVE_PALETTE, ACTIVE_FIND:
+		return true
+	}
+	return false
+}
+
+// endPrompt gives the URL bar back to the active tab after the bookmark
+// prompt, the command line, the command palette or the find prompt.
+func (b *Browser) endPrompt() {
+	theme := config.GetTheme()
+	b.bookmarkURL = ""
+	b.closeOmnibox()
+	b.Url.Blur()
+	b.Url.Prompt = theme.SearchIcon + " > "
+	b.Url.Placeholder = theme.SearchPlaceholder
+	b.Url.SetValue(b.Tabs.ActiveTab().URL())
+	b.ActivePane = ACTIVE_VIEWPORT
+}
+
+// startAnimation starts the animation ticker when the active tab has images to
+// play and it isn't running already.
+func (b *Browser) startAnimation() tea.Cmd {
+	if b.isAnimating || !b.Tabs.ActiveTab().IsAnimated() {
+		return nil
+	}
+
+	b.isAnimating = true
+	return animationTickCmd()
+}
+
+// showImageHints labels the images in view so one can be opened in the image
+// viewer. With a single image in view it is opened straight away.
+func (b *Browser) showImageHints() {
+	visible := b.Tabs.ActiveTab().VisibleImages(b.Viewport.YOffset, b.Viewport.Height)
+	switch len(visible) {
+	case 0:
+		b.Logger.Add("No images in view")
+		return
+	case 1:
+		b.openImageViewer(visible[0])
+		return
+	}
+
+	b.imageHints = visible[:min(len(visible), maxHints)]
+	labels := make(map[*dom.InlineImage]string, len(b.imageHints))
+	for i, img := range b.imageHints {
+		label := style.LogoStyle().Render(strconv.Itoa(i+1)) + " " + dom.ItalicStyle.Render(img.Alt)
+		labels[img] = zone.Mark(fmt.Sprintf("%s%d", IMAGE_HINT_ID, i), label)
+	}
+
+	b.ActivePane = ACTIVE_IMAGE_HINTS
+	b.Tabs.ActiveTab().ShowImageHints(labels)
+	b.Viewport.SetContent(b.pageContent())
+}
+
+// selectImageHint opens the image labelled key, or goes back to the page for
+// any other key.
+func (b *Browser) selectImageHint(key string) {
+	hints := b.imageHints
+	b.hideImageHints()
+
+	if n, err := strconv.Atoi(key); err == nil && n >= 1 && n <= len(hints) {
+		b.openImageViewer(hints[n-1])
+	}
+}
+
+func (b *Browser) hideImageHints() {
+	b.imageHints = nil
+	b.ActivePane = ACTIVE_VIEWPORT
+	b.Tabs.ActiveTab().ShowImageHints(nil)
+	b.Viewport.SetContent(b.pageContent())
+}
+
+// showDetailsHints labels the <details> in view so one can be opened or
+// closed. With a single one in view it is toggled straight away.
+func (b *Browser) showDetailsHints() {
+	tab := b.Tabs.ActiveTab()
+	inView := tab.detailsInView(b.Viewport.YOffset, b.Viewport.Height)
+	switch len(inView) {
+	case 0:
+		b.showMessage("No details on this page")
+		return
+	case 1:
+		b.toggleDetails(inView[0])
+		return
+	}
+
+	b.detailsHints = inView[:min(len(inView), maxHints)]
+	labels := make(map[int]string, len(b.detailsHints))
+	for i, index := range b.detailsHints {
+		// unpadded, the label takes the place of the marker
+		labels[index] = style.LogoStyle().Padding(0).Render(strconv.Itoa(i + 1))
+	}
+
+	b.ActivePane = ACTIVE_DETAILS_HINTS
+	tab.ShowDetailsHints(labels)
+	b.Viewport.SetContent(b.pageContent())
+}
+
+// selectDetailsHint opens or closes the <details> labelled key, or goes back
+// to the page for any other key.
+func (b *Browser) selectDetailsHint(key string) {
+	hints := b.detailsHints
+	b.detailsHints = nil
+	b.ActivePane = ACTIVE_VIEWPORT
+	b.Tabs.ActiveTab().ShowDetailsHints(nil)
+
+	if n, err := strconv.Atoi(key); err == nil && n >= 1 && n <= len(hints) {
+		b.toggleDetails(hints[n-1])
+	} else {
+		b.Viewport.SetContent(b.pageContent())
+	}
+}
+
+func (b *Browser) openImageViewer(img *dom.InlineImage) {
+	if b.ActivePane == ACTIVE_IMAGE_HINTS {
+		b.hideImageHints()
+	}
+
+	b.viewer = newImageViewer(img)
+	b.viewer.render(b.Width, b.Height, b.IsKitty)
+	b.ActivePane = ACTIVE_IMAGE_VIEWER
+}
+
+// closeImageViewer goes back to the page. The viewport wasn't touched while
+// the viewer was open, so it is still at the same scroll position.
+func (b *Browser) closeImageViewer() {
+	b.viewer = nil
+	b.ActivePane = ACTIVE_VIEWPORT
+}
+
+// showActiveTab puts the active tab's page in the viewport after it was
+// navigated.
+func (b *Browser) showActiveTab() tea.Cmd {
+	b.Viewport.SetContent(b.pageContent())
+	b.Viewport.SetYOffset(b.Tabs.ActiveTab().scrollPos)
+	return updateURLCmd(b.Tabs.ActiveTab().url)
+}
+
+// recordScrollPos keeps the line the page is scrolled to in the active tab,
+// for switching back to it and for sessions.
+func (b *Browser) recordScrollPos() {
+	if b.Ready {
+		b.Tabs.SetScrollPos(b.Viewport.YOffset)
+	}
+}
+
+// saveSession writes the open tabs to the current session.
+func (b *Browser) saveSession() {
+	b.recordScrollPos()
+	if err := SaveSession(b.SessionName, b.Tabs); err != nil {
+		b.Logger.Add(fmt.Sprintf("Could not save session: %v", err))
+	}
+}
+
+// saveHistory writes the visits recorded since the history was last saved.
+func (b *Browser) saveHistory() {
+	if b.History == nil {
+		return
+	}
+	if err := b.History.Flush(); err != nil {
+		b.Logger.Add(fmt.Sprintf("Could not save history: %v", err))
+	}
+}
+
+// loadSession replaces the open tabs with the ones saved in the session
+// called name, and keeps saving to it from then on.
+func (b *Browser) loadSession(name string) error {
+	session, err := LoadSession(name)
+	if err != nil {
+		return err
+	}
+
+	b.SessionName = name
+	b.Tabs.Restore(session, b.WordWrap(), b.IsKitty)
+	b.Viewport.SetContent(b.pageContent())
+	b.Viewport.SetYOffset(b.Tabs.ActiveTab().scrollPos)
+	b.Url.SetValue(b.Tabs.ActiveTab().url)
+	return nil
+}
+
+// quit saves the session and exits.
+func (b *Browser) quit() tea.Cmd {
+	b.saveSession()
+	b.saveHistory()
+	return tea.Quit
+}
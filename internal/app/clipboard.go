@@ -0,0 +1,27 @@
+package app
+
+import (
+	"os"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
+)
+
+// copyToClipboardCmd puts text on the system clipboard, falling back to
+// asking the terminal to do it (OSC 52) when there is no clipboard tool, as
+// over SSH. It runs as a command, out of Update, since the clipboard tool is
+// a process to wait for.
+//
+// The renderer owns the terminal, but the OSC 52 sequence is written in a
+// single write, as is each frame the renderer flushes, so it lands between
+// two frames rather than inside one. It neither prints nor moves the cursor,
+// which leaves the renderer's idea of the screen right.
+func copyToClipboardCmd(text string) tea.Cmd {
+	return func() tea.Msg {
+		if err := clipboard.WriteAll(text); err != nil {
+			termenv.NewOutput(os.Stdout).Copy(text)
+		}
+		return nil
+	}
+}
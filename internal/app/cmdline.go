@@ -0,0 +1,140 @@
+package app
+
+import (
+	"fmt"
+	"ruppi/internal/config"
+	"ruppi/pkg/httpclient"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// commands are the commands of the ":" command line, offered as completions
+// alongside the action names.
+var commands = []string{"open", "tabopen", "set", "session", "quit"}
+
+// openCommandLine turns the URL bar into a ":" command line. Commands and
+// action names complete with the right arrow.
+func (b *Browser) openCommandLine() tea.Cmd {
+	names := append([]string{}, commands...)
+	for _, info := range actions {
+		names = append(names, string(info.action))
+	}
+
+	b.closeOmnibox()
+	b.ActivePane = ACTIVE_COMMAND_LINE
+	b.Url.Prompt = ":"
+	b.Url.Placeholder = "open, tabopen, set, session, quit or an action"
+	b.Url.SetValue("")
+	b.Url.SetSuggestions(names)
+	return b.Url.Focus()
+}
+
+// runCommand runs a line typed in the command line.
+func (b *Browser) runCommand(line string) tea.Cmd {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	name, args := fields[0], fields[1:]
+	rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), name))
+
+	switch name {
+	case "open", "o", "e":
+		if rest == "" {
+			b.showMessage("Usage: :open <url>")
+			return nil
+		}
+		b.Tabs.ChangeActiveTabURL(b.resolveURL(rest), b.WordWrap(), b.IsKitty)
+		return b.showActiveTab()
+
+	case "tabopen", "t", "tabnew":
+		if rest == "" {
+			return createNewTabCmd("")
+		}
+		return createNewTabCmd(b.resolveURL(rest))
+
+	case "quit", "q", "qa", "quitall", "wq":
+		return b.quit()
+
+	case "set":
+		return b.setCommand(args)
+
+	case "session":
+		return b.sessionCommand(args)
+	}
+
+	if _, ok := b.keymap().bindings[Action(name)]; ok {
+		return b.runAction(Action(name), 0)
+	}
+
+	b.showMessage(fmt.Sprintf("Unknown command: %s", name))
+	return nil
+}
+
+// setCommand changes a setting, given as "key value" or "key=value". Without
+// arguments it shows every setting.
+func (b *Browser) setCommand(args []string) tea.Cmd {
+	if len(args) == 0 {
+		b.Tabs.ChangeActiveTabURL("about:config", b.WordWrap(), b.IsKitty)
+		return b.showActiveTab()
+	}
+
+	key, value, ok := strings.Cut(strings.Join(args, " "), "=")
+	if !ok {
+		key, value = args[0], strings.Join(args[1:], " ")
+	}
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+	if err := config.Set(key, value); err != nil {
+		b.showMessage(err.Error())
+		return nil
+	}
+
+	// keys and theme settings take effect straight away
+	b.Keymap, _ = NewKeymap()
+	httpclient.SetHelpKeys(b.Keymap.HelpEntries)
+	b.Tabs.Render(b.WordWrap(), b.IsKitty)
+	b.Viewport.SetContent(b.pageContent())
+	b.showMessage(fmt.Sprintf("%s = %s", key, value))
+	return nil
+}
+
+// sessionCommand saves the open tabs to, or loads them from, a named session.
+func (b *Browser) sessionCommand(args []string) tea.Cmd {
+	if len(args) == 0 || len(args) > 2 {
+		b.showMessage("Usage: :session save|load [<name>]")
+		return nil
+	}
+
+	name := b.SessionName
+	if len(args) == 2 {
+		name = args[1]
+	}
+
+	switch args[0] {
+	case "save":
+		b.recordScrollPos()
+		if err := SaveSession(name, b.Tabs); err != nil {
+			b.showMessage(err.Error())
+			return nil
+		}
+		b.showMessage(fmt.Sprintf("Saved session %s", name))
+	case "load":
+		if err := b.loadSession(name); err != nil {
+			b.showMessage(err.Error())
+			return nil
+		}
+		b.showMessage(fmt.Sprintf("Loaded session %s", name))
+	default:
+		b.showMessage("Usage: :session save|load [<name>]")
+	}
+	return nil
+}
+
+// showMessage shows text in place of the URL until the next key press.
+func (b *Browser) showMessage(text string) {
+	b.message = text
+	b.Logger.Add(text)
+}
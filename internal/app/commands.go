@@ -1,16 +1,29 @@
 package app
 
-import tea "github.com/charmbracelet/bubbletea"
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
 
 const (
 	TAB_ID = "ruppi_tab_id_"
+
+	animationInterval = 50 * time.Millisecond
 )
 
 type updateScrollPosition int
 type updateURL string
 type newTabMsg string
 type changeTabMsg int
+type navigateMsg string
+type actionMsg struct {
+	action Action
+	count  int
+}
 type refreshViewport bool
+type animationTickMsg time.Time
+type sessionSaveMsg time.Time
 
 func updateScrollPositionCmd(line int) tea.Cmd {
 	return func() tea.Msg {
@@ -35,8 +48,40 @@ func createChangeTabCmd(tabId int) tea.Cmd {
 	}
 }
 
+// navigateCmd opens url in the active tab.
+func navigateCmd(url string) tea.Cmd {
+	return func() tea.Msg {
+		return navigateMsg(url)
+	}
+}
+
+// runActionCmd does action as if its key binding had been pressed with count
+// typed before it.
+func runActionCmd(action Action, count int) tea.Cmd {
+	return func() tea.Msg {
+		return actionMsg{action: action, count: count}
+	}
+}
+
 func toggleInspectorWindow(toggle bool) tea.Cmd {
 	return func() tea.Msg {
 		return refreshViewport(toggle)
 	}
 }
+
+func animationTickCmd() tea.Cmd {
+	return tea.Tick(animationInterval, func(t time.Time) tea.Msg {
+		return animationTickMsg(t)
+	})
+}
+
+// sessionSaveCmd saves the session again after interval seconds. An interval
+// of 0 turns periodic saving off.
+func sessionSaveCmd(interval int) tea.Cmd {
+	if interval <= 0 {
+		return nil
+	}
+	return tea.Tick(time.Duration(interval)*time.Second, func(t time.Time) tea.Msg {
+		return sessionSaveMsg(t)
+	})
+}
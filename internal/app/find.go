@@ -0,0 +1,124 @@
+package app
+
+import (
+	"fmt"
+	"ruppi/internal/dom"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// findState is the last search in the page. Its matches belong to the page
+// tab showed at url and width; n and N search any other page again for
+// query.
+type findState struct {
+	query   string
+	matches []dom.Match
+	current int
+
+	tab   *Tab
+	url   string
+	width int
+}
+
+// openFind turns the URL bar into the find prompt, which searches the page
+// as the query is typed.
+func (b *Browser) openFind() tea.Cmd {
+	b.findOrigin = b.Viewport.YOffset
+	b.closeOmnibox()
+	b.ActivePane = ACTIVE_FIND
+	b.Url.Prompt = "/"
+	b.Url.Placeholder = "find in page"
+	b.Url.SetValue("")
+	return b.Url.Focus()
+}
+
+// cancelFind closes the find prompt, drops the highlights and goes back to
+// where the page was scrolled before.
+func (b *Browser) cancelFind() {
+	b.find = findState{}
+	b.endPrompt()
+	b.Viewport.SetContent(b.pageContent())
+	b.Viewport.SetYOffset(b.findOrigin)
+}
+
+// findInPage searches the active tab for query and moves to the first match
+// at or below line from, or back to from when nothing matches.
+func (b *Browser) findInPage(query string, from int) {
+	tab := b.Tabs.ActiveTab()
+	b.find = findState{
+		query:   query,
+		matches: dom.FindText(tab.rendered, query),
+		tab:     tab,
+		url:     tab.url,
+		width:   tab.renderedWidth,
+	}
+	for i, m := range b.find.matches {
+		if m.Line >= from {
+			b.find.current = i
+			break
+		}
+	}
+	b.showMatch()
+	if len(b.find.matches) == 0 {
+		b.Viewport.SetYOffset(from)
+	}
+}
+
+// findNext moves delta matches forward, or back for a negative delta,
+// wrapping around at either end.
+func (b *Browser) findNext(delta int) {
+	if b.find.query == "" {
+		b.showMessage("No previous search")
+		return
+	}
+	if !b.findIsCurrent() {
+		b.findInPage(b.find.query, b.Viewport.YOffset)
+	} else if n := len(b.find.matches); n > 0 {
+		b.find.current = ((b.find.current+delta)%n + n) % n
+		b.showMatch()
+	}
+
+	if len(b.find.matches) == 0 {
+		b.showMessage(fmt.Sprintf("Not found: %s", b.find.query))
+	}
+}
+
+// showMatch redraws the highlights and scrolls the current match into view.
+func (b *Browser) showMatch() {
+	b.Viewport.SetContent(b.pageContent())
+	if len(b.find.matches) == 0 {
+		return
+	}
+
+	line := b.find.matches[b.find.current].Line
+	if line < b.Viewport.YOffset || line >= b.Viewport.YOffset+b.Viewport.Height {
+		b.Viewport.SetYOffset(max(line-b.Viewport.Height/3, 0))
+	}
+}
+
+// findIsCurrent reports whether the last search was of the page shown now.
+func (b *Browser) findIsCurrent() bool {
+	tab := b.Tabs.ActiveTab()
+	return b.find.query != "" && b.find.tab == tab && b.find.url == tab.url && b.find.width == tab.renderedWidth
+}
+
+// findStatus is the "3/17" shown in the status bar while the page has a
+// search.
+func (b *Browser) findStatus() string {
+	if !b.findIsCurrent() {
+		return ""
+	}
+	if len(b.find.matches) == 0 {
+		return "0/0"
+	}
+	return fmt.Sprintf("%d/%d", b.find.current+1, len(b.find.matches))
+}
+
+// pageContent is the active tab's page with the matches of the last search
+// highlighted.
+func (b *Browser) pageContent() string {
+	if !b.findIsCurrent() {
+		return b.Tabs.Rendered()
+	}
+	return dom.HighlightMatches(b.Tabs.Rendered(), b.find.matches, b.find.current)
+}
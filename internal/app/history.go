@@ -0,0 +1,81 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"ruppi/internal/history"
+	"strings"
+	"time"
+)
+
+// maxCompletions is how many history completions the URL bar cycles through.
+const maxCompletions = 10
+
+// historyPage lists the visited pages by day, most recent first, or the ones
+// matching query when there is one.
+func historyPage(store *history.Store, query string) string {
+	var sb strings.Builder
+	sb.WriteString("<title>History</title>\n<h1>History</h1>\n")
+
+	if query != "" {
+		results := store.Search(query)
+		fmt.Fprintf(&sb, "<p>%d pages matching <code>%s</code></p>\n", len(results), html.EscapeString(query))
+		sb.WriteString("<ul>\n")
+		for _, e := range results {
+			writeHistoryEntry(&sb, e, e.LastVisit.Format("2006-01-02 15:04"))
+		}
+		sb.WriteString("</ul>\n")
+		return sb.String()
+	}
+
+	entries := store.Recent()
+	if len(entries) == 0 {
+		sb.WriteString("<p>No pages visited yet.</p>\n")
+		return sb.String()
+	}
+
+	sb.WriteString("<p>Search it with <code>about:history?q=query</code>.</p>\n")
+	day := ""
+	for _, e := range entries {
+		if d := dayLabel(e.LastVisit); d != day {
+			if day != "" {
+				sb.WriteString("</ul>\n")
+			}
+			day = d
+			fmt.Fprintf(&sb, "<h2>%s</h2>\n<ul>\n", day)
+		}
+		writeHistoryEntry(&sb, e, e.LastVisit.Format("15:04"))
+	}
+	sb.WriteString("</ul>\n")
+	return sb.String()
+}
+
+func writeHistoryEntry(sb *strings.Builder, e history.Entry, when string) {
+	title := e.Title
+	if title == "" {
+		title = e.URL
+	}
+
+	fmt.Fprintf(sb, `<li><code>%s</code> <a href="%s">%s</a>`, when, html.EscapeString(e.URL), html.EscapeString(title))
+	if e.Visits > 1 {
+		fmt.Fprintf(sb, " <i>%d visits</i>", e.Visits)
+	}
+	sb.WriteString("</li>\n")
+}
+
+// dayLabel names the day t falls on relative to today.
+func dayLabel(t time.Time) string {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch {
+	case !t.Before(today):
+		return "Today"
+	case !t.Before(today.AddDate(0, 0, -1)):
+		return "Yesterday"
+	case !t.Before(today.AddDate(0, 0, -6)):
+		return t.Format("Monday")
+	default:
+		return t.Format("Monday, 2 January 2006")
+	}
+}
@@ -0,0 +1,184 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"ruppi/internal/config"
+	"ruppi/internal/dom"
+	"ruppi/pkg/helper"
+	"ruppi/pkg/kitty"
+	"ruppi/pkg/style"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	IMAGE_HINT_ID = "ruppi_image_hint_"
+
+	viewerZoomStep = 1.25
+	viewerMaxZoom  = 16.0
+	// viewerPanStep is how far one key press pans, as a fraction of the
+	// visible part of the image.
+	viewerPanStep = 0.1
+)
+
+// imageViewer shows a single image over the whole terminal, with zoom and pan.
+type imageViewer struct {
+	image *dom.InlineImage
+
+	zoom float64
+	// centerX and centerY are the center of the view as a fraction of the
+	// image size.
+	centerX float64
+	centerY float64
+
+	message  string
+	rendered string
+	cols     int
+	rows     int
+}
+
+func newImageViewer(img *dom.InlineImage) *imageViewer {
+	return &imageViewer{image: img, zoom: 1, centerX: 0.5, centerY: 0.5}
+}
+
+// Update handles a key press and reports whether the viewer should close.
+func (v *imageViewer) Update(msg tea.KeyMsg) bool {
+	v.message = ""
+	pan := viewerPanStep / v.zoom
+
+	switch msg.String() {
+	case "esc", "q":
+		return true
+	case "+", "=":
+		v.zoom = math.Min(v.zoom*viewerZoomStep, viewerMaxZoom)
+	case "-":
+		v.zoom = math.Max(v.zoom/viewerZoomStep, 1)
+	case "0":
+		v.zoom, v.centerX, v.centerY = 1, 0.5, 0.5
+	case "left", "h":
+		v.centerX -= pan
+	case "right", "l":
+		v.centerX += pan
+	case "up", "k":
+		v.centerY -= pan
+	case "down", "j":
+		v.centerY += pan
+	case "s":
+		if file, err := v.save(); err != nil {
+			v.message = fmt.Sprintf("Could not save image: %v", err)
+		} else {
+			v.message = "Saved to " + file
+		}
+	}
+
+	// keep the visible region inside the image
+	half := 0.5 / v.zoom
+	v.centerX = math.Max(half, math.Min(1-half, v.centerX))
+	v.centerY = math.Max(half, math.Min(1-half, v.centerY))
+	return false
+}
+
+// render encodes the visible region of the image for a width x height screen.
+func (v *imageViewer) render(width, height int, isKitty bool) {
+	cfg := config.GetSixelConfig()
+	frame := v.image.CurrentFrame()
+	bounds := frame.Bounds()
+
+	regionWidth := float64(bounds.Dx()) / v.zoom
+	regionHeight := float64(bounds.Dy()) / v.zoom
+	x0 := bounds.Min.X + int(v.centerX*float64(bounds.Dx())-regionWidth/2)
+	y0 := bounds.Min.Y + int(v.centerY*float64(bounds.Dy())-regionHeight/2)
+	region := image.Rect(x0, y0, x0+max(1, int(regionWidth)), y0+max(1, int(regionHeight)))
+
+	// the last line is kept for the status bar
+	rows := max(1, height-1)
+	scaled := helper.ScaleRegion(frame, region, width*cfg.CellWidth, rows*cfg.CellHeight)
+	v.cols = min(width, int(math.Ceil(float64(scaled.Bounds().Dx())/float64(cfg.CellWidth))))
+	v.rows = min(rows, int(math.Ceil(float64(scaled.Bounds().Dy())/float64(cfg.CellHeight))))
+
+	var err error
+	if isKitty {
+		v.rendered, err = kitty.Encode(scaled, v.image.ID, v.cols, v.rows, 0, 0)
+	} else {
+		v.rendered, err = dom.SixelEncoder(scaled.Bounds().Dx(), scaled.Bounds().Dy()).EncodeToString(scaled)
+	}
+	if err != nil {
+		v.rendered = ""
+		v.message = fmt.Sprintf("Could not draw image: %v", err)
+	}
+}
+
+func (v *imageViewer) View(width, height int) string {
+	lines := make([]string, max(2, height))
+
+	top := max(0, (len(lines)-1-v.rows)/2)
+	left := max(0, (width-v.cols)/2)
+	lines[top] = strings.Repeat(" ", left) + v.rendered
+
+	status := v.message
+	if status == "" {
+		name := v.image.Alt
+		if name == "" {
+			name = v.image.URL
+		}
+		status = fmt.Sprintf("%s  %.0f%%  +/- zoom  hjkl pan  0 reset  s save  esc back", name, v.zoom*100)
+	}
+	lines[len(lines)-1] = style.StatusColor().Width(width).Render(helper.TruncateString(status, width, true))
+
+	return strings.Join(lines, "\n")
+}
+
+// save writes the original image to the download directory and returns the
+// path of the new file.
+func (v *imageViewer) save() (string, error) {
+	anim := v.image.Animation()
+	data, format := anim.Data, anim.Format
+	if len(data) == 0 {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, v.image.CurrentFrame()); err != nil {
+			return "", err
+		}
+		data, format = buf.Bytes(), "png"
+	}
+
+	name := "image"
+	if u, err := url.Parse(v.image.URL); err == nil && u.Scheme != "data" {
+		if base := path.Base(u.Path); base != "." && base != "/" && base != "" {
+			name = base
+		}
+	}
+	if filepath.Ext(name) == "" {
+		if format == "jpeg" {
+			format = "jpg"
+		}
+		name += "." + format
+	}
+
+	dir := config.GetDownloadDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	file := filepath.Join(dir, name)
+	ext := filepath.Ext(name)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			break
+		}
+		file = filepath.Join(dir, fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), i, ext))
+	}
+
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return "", err
+	}
+	return file, nil
+}
@@ -0,0 +1,378 @@
+package app
+
+import (
+	"fmt"
+	"ruppi/internal/config"
+	"ruppi/pkg/httpclient"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// Action is something the browser can do from a key binding.
+type Action string
+
+const (
+	ACTION_QUIT             Action = "quit"
+	ACTION_FOCUS_URL        Action = "focus-url"
+	ACTION_TOGGLE_INSPECTOR Action = "toggle-inspector"
+	ACTION_IMAGE_VIEWER     Action = "image-viewer"
+	ACTION_BACK             Action = "back"
+	ACTION_FORWARD          Action = "forward"
+	ACTION_BOOKMARK         Action = "bookmark"
+	ACTION_NEW_TAB          Action = "new-tab"
+	ACTION_NEXT_TAB         Action = "next-tab"
+	ACTION_PREV_TAB         Action = "prev-tab"
+	ACTION_SCROLL_DOWN      Action = "scroll-down"
+	ACTION_SCROLL_UP        Action = "scroll-up"
+	ACTION_SCROLL_LEFT      Action = "scroll-left"
+	ACTION_SCROLL_RIGHT     Action = "scroll-right"
+	ACTION_HALF_PAGE_DOWN   Action = "half-page-down"
+	ACTION_HALF_PAGE_UP     Action = "half-page-up"
+	ACTION_PAGE_DOWN        Action = "page-down"
+	ACTION_PAGE_UP          Action = "page-up"
+	ACTION_TOP              Action = "top"
+	ACTION_BOTTOM           Action = "bottom"
+	ACTION_HELP             Action = "help"
+	ACTION_YANK_URL         Action = "yank-url"
+	ACTION_COMMAND_LINE     Action = "command-line"
+	ACTION_COMMAND_PALETTE  Action = "command-palette"
+	ACTION_FIND             Action = "find"
+	ACTION_FIND_NEXT        Action = "find-next"
+	ACTION_FIND_PREV        Action = "find-prev"
+	ACTION_OUTLINE          Action = "toggle-outline"
+	ACTION_READER           Action = "toggle-reader"
+	ACTION_DETAILS          Action = "toggle-details"
+)
+
+// actionInfo describes an action for the help overlay. group is the help
+// column it is listed in.
+type actionInfo struct {
+	action      Action
+	description string
+	group       int
+}
+
+// actions lists every action in the order the help overlay shows them.
+var actions = []actionInfo{
+	{ACTION_SCROLL_DOWN, "scroll down", 0},
+	{ACTION_SCROLL_UP, "scroll up", 0},
+	{ACTION_SCROLL_LEFT, "scroll left", 0},
+	{ACTION_SCROLL_RIGHT, "scroll right", 0},
+	{ACTION_HALF_PAGE_DOWN, "half page down", 0},
+	{ACTION_HALF_PAGE_UP, "half page up", 0},
+	{ACTION_PAGE_DOWN, "page down", 0},
+	{ACTION_PAGE_UP, "page up", 0},
+	{ACTION_TOP, "go to top", 0},
+	{ACTION_BOTTOM, "go to bottom", 0},
+
+	{ACTION_FOCUS_URL, "open the URL bar", 1},
+	{ACTION_BACK, "go back", 1},
+	{ACTION_FORWARD, "go forward", 1},
+	{ACTION_NEW_TAB, "new tab", 1},
+	{ACTION_NEXT_TAB, "next tab", 1},
+	{ACTION_PREV_TAB, "previous tab", 1},
+	{ACTION_BOOKMARK, "bookmark the page", 1},
+	{ACTION_YANK_URL, "copy the URL", 1},
+	{ACTION_COMMAND_PALETTE, "command palette", 1},
+
+	{ACTION_FIND, "find in page", 2},
+	{ACTION_FIND_NEXT, "next match", 2},
+	{ACTION_FIND_PREV, "previous match", 2},
+	{ACTION_OUTLINE, "page outline", 2},
+	{ACTION_READER, "reader mode", 2},
+	{ACTION_DETAILS, "open/close details", 2},
+	{ACTION_IMAGE_VIEWER, "view an image", 2},
+	{ACTION_TOGGLE_INSPECTOR, "toggle the inspector", 2},
+	{ACTION_COMMAND_LINE, "enter a command", 2},
+	{ACTION_HELP, "toggle this help", 2},
+	{ACTION_QUIT, "quit", 2},
+}
+
+// defaultKeys returns the keys of each action before [keys] is applied. The
+// keys that predate [keys] still come from the [ruppi] section.
+func defaultKeys(theme config.Theme) map[Action][]string {
+	return map[Action][]string{
+		ACTION_QUIT:             {theme.QuitKey},
+		ACTION_FOCUS_URL:        {theme.SearchKey, "/"},
+		ACTION_TOGGLE_INSPECTOR: {theme.InspectorToggleKey},
+		ACTION_IMAGE_VIEWER:     {theme.ImageViewerKey},
+		ACTION_BACK:             {theme.BackKey},
+		ACTION_FORWARD:          {theme.ForwardKey},
+		ACTION_BOOKMARK:         {theme.BookmarkKey},
+		ACTION_NEW_TAB:          {"ctrl+t"},
+		ACTION_NEXT_TAB:         {"gt"},
+		ACTION_PREV_TAB:         {"gT"},
+		ACTION_SCROLL_DOWN:      {"down", "j"},
+		ACTION_SCROLL_UP:        {"up", "k"},
+		ACTION_SCROLL_LEFT:      {"left", "h"},
+		ACTION_SCROLL_RIGHT:     {"right", "l"},
+		ACTION_HALF_PAGE_DOWN:   {"d"},
+		ACTION_HALF_PAGE_UP:     {"u", "ctrl+u"},
+		ACTION_PAGE_DOWN:        {"pgdown", "space", "f"},
+		ACTION_PAGE_UP:          {"pgup", "b"},
+		ACTION_TOP:              {"gg", "home"},
+		ACTION_BOTTOM:           {"G", "end"},
+		ACTION_HELP:             {"f1"},
+		ACTION_YANK_URL:         {"yy"},
+		ACTION_COMMAND_LINE:     {":"},
+		ACTION_COMMAND_PALETTE:  {"ctrl+p"},
+		ACTION_FIND:             {"ctrl+f"},
+		ACTION_FIND_NEXT:        {"n"},
+		ACTION_FIND_PREV:        {"N"},
+		ACTION_OUTLINE:          {"O"},
+		ACTION_READER:           {"R"},
+		ACTION_DETAILS:          {"za"},
+	}
+}
+
+// vimKeys returns the keys of the vim preset: the defaults with history on
+// H/L, tabs on J/K, ctrl+d freed up for scrolling and / finding in the page.
+func vimKeys(theme config.Theme) map[Action][]string {
+	keys := defaultKeys(theme)
+	keys[ACTION_BACK] = []string{"H", theme.BackKey}
+	keys[ACTION_FORWARD] = []string{"L", theme.ForwardKey}
+	keys[ACTION_NEXT_TAB] = []string{"J", "gt"}
+	keys[ACTION_PREV_TAB] = []string{"K", "gT"}
+	keys[ACTION_HALF_PAGE_DOWN] = []string{"d", "ctrl+d"}
+	keys[ACTION_HALF_PAGE_UP] = []string{"u", "ctrl+u"}
+	keys[ACTION_PAGE_DOWN] = []string{"ctrl+f", "pgdown", "space"}
+	keys[ACTION_PAGE_UP] = []string{"ctrl+b", "pgup"}
+	keys[ACTION_FOCUS_URL] = []string{"o", theme.SearchKey}
+	keys[ACTION_FIND] = []string{"/"}
+	keys[ACTION_NEW_TAB] = []string{"t", "ctrl+t"}
+	keys[ACTION_BOOKMARK] = []string{"m"}
+	return keys
+}
+
+// namedKeys are the multi-letter key names bubbletea uses, which must not be
+// read as a sequence of letters.
+var namedKeys = map[string]bool{
+	"enter": true, "esc": true, "tab": true, "space": true, "backspace": true,
+	"delete": true, "insert": true, "up": true, "down": true, "left": true,
+	"right": true, "home": true, "end": true, "pgup": true, "pgdown": true,
+}
+
+// parseKeySequence splits a key spec into the key presses it is made of:
+// "ctrl+t" is one press, "gg" and "g T" are two.
+func parseKeySequence(spec string) []string {
+	if strings.Contains(spec, " ") {
+		return strings.Fields(spec)
+	}
+	if utf8.RuneCountInString(spec) <= 1 || strings.Contains(spec, "+") || namedKeys[spec] || isFunctionKey(spec) {
+		return []string{spec}
+	}
+
+	var keys []string
+	for _, r := range spec {
+		keys = append(keys, string(r))
+	}
+	return keys
+}
+
+func isFunctionKey(spec string) bool {
+	if len(spec) < 2 || spec[0] != 'f' {
+		return false
+	}
+	for _, c := range spec[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// keyName is how bubbletea names a key press in KeyMsg.String.
+func keyName(k string) string {
+	if k == "space" {
+		return " "
+	}
+	return k
+}
+
+// Keymap maps key presses, including multi-key sequences like "gg", to
+// actions. Digits typed before a binding that isn't a digit itself are a
+// count, as in "5j".
+type Keymap struct {
+	bindings  map[Action]key.Binding
+	sequences map[Action][][]string
+
+	// pending holds the keys typed so far of an unfinished sequence, and
+	// count the count typed before it.
+	pending []string
+	count   int
+}
+
+// NewKeymap builds the keymap from the defaults and the [keys] section. It
+// also returns a problem for every unknown action in [keys].
+func NewKeymap() (*Keymap, []error) {
+	k := &Keymap{
+		bindings:  map[Action]key.Binding{},
+		sequences: map[Action][][]string{},
+	}
+
+	defaults := defaultKeys(config.GetTheme())
+	if config.GetKeyPreset() == "vim" {
+		defaults = vimKeys(config.GetTheme())
+	}
+	for _, info := range actions {
+		specs := defaults[info.action]
+		if configured, ok := config.GetKeyBindings(string(info.action)); ok {
+			specs = configured
+		}
+		k.bind(info, specs)
+	}
+
+	var errs []error
+	unknown := config.ConfiguredKeyActions()
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		if _, ok := k.bindings[Action(name)]; !ok {
+			errs = append(errs, fmt.Errorf("unknown action in [keys]: %s", name))
+		}
+	}
+	return k, errs
+}
+
+func (k *Keymap) bind(info actionInfo, specs []string) {
+	var keys, help []string
+	var sequences [][]string
+	for _, spec := range specs {
+		if spec == "" {
+			continue
+		}
+		sequence := parseKeySequence(spec)
+		sequences = append(sequences, sequence)
+		keys = append(keys, strings.Join(sequence, " "))
+		help = append(help, strings.Join(sequence, ""))
+	}
+
+	k.sequences[info.action] = sequences
+	k.bindings[info.action] = key.NewBinding(
+		key.WithKeys(keys...),
+		key.WithHelp(strings.Join(help, ", "), info.description),
+	)
+}
+
+// Press adds a key press to the pending sequence and returns the action it
+// completes, with the count typed before it (0 without one). It reports false
+// while no action is complete, either because more keys are expected or
+// because the keys aren't bound.
+func (k *Keymap) Press(keyStr string) (Action, int, bool) {
+	if k.isCountDigit(keyStr) {
+		k.count = k.count*10 + int(keyStr[0]-'0')
+		return "", 0, false
+	}
+
+	k.pending = append(k.pending, keyStr)
+	action, prefix := k.match(k.pending)
+	if action == "" && !prefix && len(k.pending) > 1 {
+		// the sequence went nowhere, so start over from this key
+		k.pending = []string{keyStr}
+		action, prefix = k.match(k.pending)
+	}
+
+	if prefix && action == "" {
+		return "", 0, false
+	}
+
+	count := k.count
+	k.Reset()
+	if action == "" {
+		return "", 0, false
+	}
+	return action, count, true
+}
+
+// isCountDigit reports whether keyStr adds to the count: a digit that isn't
+// bound itself, typed before any other key of a sequence. A count can't start
+// with 0.
+func (k *Keymap) isCountDigit(keyStr string) bool {
+	if len(k.pending) > 0 || len(keyStr) != 1 || keyStr[0] < '0' || keyStr[0] > '9' {
+		return false
+	}
+	if keyStr == "0" && k.count == 0 {
+		return false
+	}
+	action, prefix := k.match([]string{keyStr})
+	return action == "" && !prefix
+}
+
+// match returns the action bound to exactly keys, and whether keys is the
+// start of a longer sequence. Exact matches win over longer sequences.
+func (k *Keymap) match(keys []string) (Action, bool) {
+	prefix := false
+	for _, info := range actions {
+		for _, sequence := range k.sequences[info.action] {
+			if len(sequence) < len(keys) || !sameKeys(sequence[:len(keys)], keys) {
+				continue
+			}
+			if len(sequence) == len(keys) {
+				return info.action, false
+			}
+			prefix = true
+		}
+	}
+	return "", prefix
+}
+
+func sameKeys(sequence, keys []string) bool {
+	for i := range keys {
+		if keyName(sequence[i]) != keys[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Pending returns the count and keys typed so far of an unfinished
+// sequence.
+func (k *Keymap) Pending() string {
+	pending := strings.Join(k.pending, "")
+	if k.count > 0 {
+		pending = fmt.Sprint(k.count) + pending
+	}
+	return pending
+}
+
+// Reset drops an unfinished sequence and its count.
+func (k *Keymap) Reset() {
+	k.pending = nil
+	k.count = 0
+}
+
+// Binding returns the keys bound to action.
+func (k *Keymap) Binding(action Action) key.Binding {
+	return k.bindings[action]
+}
+
+// HelpGroups returns the bound actions in help overlay columns.
+func (k *Keymap) HelpGroups() [][]key.Binding {
+	var groups [][]key.Binding
+	for _, info := range actions {
+		for len(groups) <= info.group {
+			groups = append(groups, nil)
+		}
+		if binding := k.bindings[info.action]; len(binding.Keys()) > 0 {
+			groups[info.group] = append(groups[info.group], binding)
+		}
+	}
+	return groups
+}
+
+// HelpEntries lists the bound actions for the built-in pages.
+func (k *Keymap) HelpEntries() []httpclient.KeyHelp {
+	var entries []httpclient.KeyHelp
+	for _, info := range actions {
+		if binding := k.bindings[info.action]; len(binding.Keys()) > 0 {
+			entries = append(entries, httpclient.KeyHelp{
+				Action:      string(info.action),
+				Keys:        binding.Help().Key,
+				Description: info.description,
+			})
+		}
+	}
+	return entries
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"ruppi/internal/config"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/viewport"
+)
+
+func TestKeymapPress(t *testing.T) {
+	type result struct {
+		action Action
+		count  int
+		ok     bool
+	}
+	tests := []struct {
+		name   string
+		preset string
+		keys   string
+		want   []result
+	}{
+		{
+			name: "single key",
+			keys: "j",
+			want: []result{{ACTION_SCROLL_DOWN, 0, true}},
+		},
+		{
+			name: "count",
+			keys: "5j",
+			want: []result{{"", 0, false}, {ACTION_SCROLL_DOWN, 5, true}},
+		},
+		{
+			name: "count of several digits",
+			keys: "12k",
+			want: []result{{"", 0, false}, {"", 0, false}, {ACTION_SCROLL_UP, 12, true}},
+		},
+		{
+			name: "sequence",
+			keys: "gg",
+			want: []result{{"", 0, false}, {ACTION_TOP, 0, true}},
+		},
+		{
+			name: "count before sequence",
+			keys: "3gT",
+			want: []result{{"", 0, false}, {"", 0, false}, {ACTION_PREV_TAB, 3, true}},
+		},
+		{
+			name: "interrupted sequence starts over",
+			keys: "gj",
+			want: []result{{"", 0, false}, {ACTION_SCROLL_DOWN, 0, true}},
+		},
+		{
+			name: "interrupted sequence drops its count",
+			keys: "4gxj",
+			want: []result{{"", 0, false}, {"", 0, false}, {"", 0, false}, {ACTION_SCROLL_DOWN, 0, true}},
+		},
+		{
+			name: "unbound key",
+			keys: "x",
+			want: []result{{"", 0, false}},
+		},
+		{
+			name: "slash opens the URL bar",
+			keys: "/",
+			want: []result{{ACTION_FOCUS_URL, 0, true}},
+		},
+		{
+			name:   "vim slash finds",
+			preset: "vim",
+			keys:   "/",
+			want:   []result{{ACTION_FIND, 0, true}},
+		},
+		{
+			name:   "vim count before gg",
+			preset: "vim",
+			keys:   "5gg",
+			want:   []result{{"", 0, false}, {"", 0, false}, {ACTION_TOP, 5, true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.preset != "" {
+				if err := config.Set("keys-preset", tt.preset); err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { config.Set("keys-preset", "default") })
+			}
+
+			k, errs := NewKeymap()
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			for i, r := range strings.Split(tt.keys, "") {
+				action, count, ok := k.Press(r)
+				if got := (result{action, count, ok}); got != tt.want[i] {
+					t.Errorf("key %d (%s): got %+v, want %+v", i, r, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestScrollCountGoesToLine(t *testing.T) {
+	b := &Browser{Viewport: viewport.New(80, 10)}
+	b.Viewport.SetContent(strings.Repeat("line\n", 100))
+
+	tests := []struct {
+		action Action
+		count  int
+		want   int
+	}{
+		{ACTION_BOTTOM, 0, 91},
+		{ACTION_TOP, 0, 0},
+		{ACTION_TOP, 5, 4},
+		{ACTION_BOTTOM, 20, 19},
+		{ACTION_SCROLL_DOWN, 3, 22},
+		{ACTION_SCROLL_DOWN, 0, 23},
+	}
+	for _, tt := range tests {
+		b.scroll(tt.action, tt.count)
+		if b.Viewport.YOffset != tt.want {
+			t.Errorf("%s with count %d: offset %d, want %d", tt.action, tt.count, b.Viewport.YOffset, tt.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package app
+
+import (
+	"fmt"
+	"ruppi/internal/config"
+	"ruppi/pkg/fuzzy"
+	"ruppi/pkg/helper"
+	"ruppi/pkg/httpclient"
+	"ruppi/pkg/style"
+	"strings"
+
+	zone "github.com/lrstanley/bubblezone"
+	"github.com/muesli/reflow/truncate"
+)
+
+const (
+	OMNIBOX_ID = "ruppi_omnibox_"
+
+	maxOmniboxItems     = 8
+	maxOmniboxTabs      = 3
+	maxOmniboxBookmarks = 3
+	omniboxLabelWidth   = 10
+)
+
+// omniboxItem is a suggestion in the dropdown under the URL bar. Picking it
+// opens url, switches to the open tab at index tab when tab isn't -1, or
+// puts fill in the URL bar to keep typing when fill is set. In the command
+// palette it may instead run action, whose keys are shown after the title.
+type omniboxItem struct {
+	label  string
+	title  string
+	url    string
+	tab    int
+	fill   string
+	action Action
+	keys   string
+}
+
+// omnibox is the dropdown of suggestions shown while typing in the URL bar.
+// selected is -1 while nothing is highlighted, so enter goes to what was
+// typed.
+type omnibox struct {
+	items    []omniboxItem
+	selected int
+}
+
+// updateOmnibox refreshes the dropdown for what has been typed in the URL
+// bar: what enter would do with it, then matching open tabs, bookmarks and
+// history.
+func (b *Browser) updateOmnibox() {
+	b.omnibox = omnibox{selected: -1}
+
+	query := strings.TrimSpace(b.Url.Value())
+	if query == "" {
+		return
+	}
+
+	seen := map[string]bool{}
+	add := func(item omniboxItem) bool {
+		if len(b.omnibox.items) >= maxOmniboxItems || seen[item.url] {
+			return false
+		}
+		seen[item.url] = true
+		b.omnibox.items = append(b.omnibox.items, item)
+		return true
+	}
+
+	target := b.resolveURL(query)
+	label := "go to"
+	if target == httpclient.SearchURL(query) {
+		label = "search"
+		if keyword, rest, ok := strings.Cut(query, " "); ok && rest != "" {
+			if _, found := config.GetSearchEngine(keyword); found {
+				label = "search " + keyword
+			}
+		}
+	}
+	add(omniboxItem{label: label, title: query, url: target, tab: -1})
+
+	// a single word may be the start of a search engine keyword
+	if !strings.Contains(query, " ") {
+		for _, engine := range config.GetSearchEngines() {
+			if strings.HasPrefix(engine.Keyword, strings.ToLower(query)) {
+				add(omniboxItem{label: "keyword", title: engine.Keyword + " …", url: engine.URL, tab: -1, fill: engine.Keyword + " "})
+			}
+		}
+	}
+
+	items := make([]string, len(b.Tabs.Tabs))
+	for i, tab := range b.Tabs.Tabs {
+		items[i] = tab.title + " " + tab.url
+	}
+	found := 0
+	for _, m := range fuzzy.Find(query, items) {
+		tab := b.Tabs.Tabs[m.Index]
+		if tab == b.Tabs.ActiveTab() || tab.url == "" {
+			continue
+		}
+		if found < maxOmniboxTabs && add(omniboxItem{label: "tab", title: tab.title, url: tab.url, tab: m.Index}) {
+			found++
+		}
+	}
+
+	if b.Bookmarks != nil {
+		found = 0
+		for _, bookmark := range b.Bookmarks.Search(query) {
+			if found < maxOmniboxBookmarks && add(omniboxItem{label: "bookmark", title: bookmark.Title, url: bookmark.URL, tab: -1}) {
+				found++
+			}
+		}
+	}
+
+	if b.History != nil {
+		for _, entry := range b.History.Search(query) {
+			add(omniboxItem{label: "history", title: entry.Title, url: entry.URL, tab: -1})
+		}
+	}
+}
+
+// moveOmnibox highlights the next (delta 1) or previous (delta -1)
+// suggestion, passing through the typed text at either end.
+func (b *Browser) moveOmnibox(delta int) {
+	n := len(b.omnibox.items)
+	if n == 0 {
+		return
+	}
+	b.omnibox.selected = (b.omnibox.selected+1+delta+n+1)%(n+1) - 1
+}
+
+// selectedOmniboxItem returns the highlighted suggestion, or nil.
+func (b *Browser) selectedOmniboxItem() *omniboxItem {
+	if b.omnibox.selected < 0 || b.omnibox.selected >= len(b.omnibox.items) {
+		return nil
+	}
+	return &b.omnibox.items[b.omnibox.selected]
+}
+
+func (b *Browser) closeOmnibox() {
+	b.omnibox = omnibox{selected: -1}
+	b.Url.SetSuggestions(nil)
+}
+
+// omniboxView draws the dropdown, one line per suggestion.
+func (b *Browser) omniboxView(width int) []string {
+	lines := make([]string, 0, len(b.omnibox.items))
+	for i, item := range b.omnibox.items {
+		rowStyle := style.OmniboxStyle()
+		if i == b.omnibox.selected {
+			rowStyle = style.OmniboxSelectedStyle()
+		}
+
+		title := item.title
+		if title == "" {
+			title = item.url
+		}
+		text := helper.TruncateString(item.label, omniboxLabelWidth-1, false) + " " + title
+		if item.url != title && item.url != "" {
+			text += "  " + item.url
+		}
+		if item.keys != "" {
+			text += "  " + item.keys
+		}
+
+		row := rowStyle.Width(width).Render(truncate.StringWithTail(" "+text, uint(max(width-1, 0)), "..."))
+		lines = append(lines, zone.Mark(fmt.Sprintf("%s%d", OMNIBOX_ID, i), row))
+	}
+	return lines
+}
+
+// resolveURL turns what was typed in the URL bar into the address to load:
+// a history completion, an anchor of the current page, a bookmark search, a
+// URL or a web search.
+func (b *Browser) resolveURL(input string) string {
+	if completion, ok := b.completions[input]; ok {
+		return completion
+	}
+	if strings.HasPrefix(input, "#") && !strings.Contains(input, " ") {
+		page, _, _ := strings.Cut(b.Tabs.ActiveTab().URL(), "#")
+		return page + input
+	}
+	if query, ok := strings.CutPrefix(input, "*"); ok {
+		return b.bookmarkSearchURL(strings.TrimSpace(query))
+	}
+	if address, ok := httpclient.NormalizeURL(input); ok {
+		return address
+	}
+	return httpclient.SearchURL(input)
+}
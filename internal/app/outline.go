@@ -0,0 +1,156 @@
+package app
+
+import (
+	"fmt"
+	"ruppi/internal/config"
+	"ruppi/internal/dom"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	zone "github.com/lrstanley/bubblezone"
+	"github.com/muesli/reflow/truncate"
+)
+
+const (
+	OUTLINE_ID = "ruppi_outline_"
+
+	outlinePaneWidth = 32
+)
+
+// outlineEntry is a heading of the page and the line it is rendered on.
+type outlineEntry struct {
+	level int
+	text  string
+	line  int
+}
+
+// toggleOutline shows the outline of the page next to it, with the keyboard
+// in the outline, or hides it again.
+func (b *Browser) toggleOutline() tea.Cmd {
+	b.showOutline = !b.showOutline
+	if b.showOutline {
+		b.ActivePane = ACTIVE_OUTLINE
+		b.outlineSelected = max(b.currentSection(), 0)
+	} else if b.ActivePane == ACTIVE_OUTLINE {
+		b.ActivePane = ACTIVE_VIEWPORT
+	}
+
+	// the page is narrower next to the outline
+	if b.Tabs.ActiveTab().renderedWidth != b.WordWrap() {
+		b.Tabs.Render(b.WordWrap(), b.IsKitty)
+		b.Viewport.SetContent(b.pageContent())
+	}
+	return toggleInspectorWindow(b.IsInspectorOpen)
+}
+
+// outlineWidth is how many columns the outline takes from the page.
+func (b *Browser) outlineWidth() int {
+	if !b.showOutline {
+		return 0
+	}
+	return min(outlinePaneWidth, b.Width/3)
+}
+
+// pageWidth is the width of the viewport, which shares the row inside the
+// app's padding with the outline while it is shown.
+func (b *Browser) pageWidth() int {
+	return b.Width - 2 - b.outlineWidth()
+}
+
+// updateOutline handles a key while the outline has the keyboard.
+func (b *Browser) updateOutline(keyStr string) tea.Cmd {
+	outline := b.Tabs.ActiveTab().outline
+
+	switch keyStr {
+	case "up", "k":
+		b.outlineSelected = max(b.outlineSelected-1, 0)
+	case "down", "j":
+		b.outlineSelected = min(b.outlineSelected+1, len(outline)-1)
+	case "enter":
+		b.jumpToSection(b.outlineSelected)
+		b.ActivePane = ACTIVE_VIEWPORT
+	case "esc":
+		b.ActivePane = ACTIVE_VIEWPORT
+	default:
+		// other bindings act on the page as usual
+		b.ActivePane = ACTIVE_VIEWPORT
+		if action, count, ok := b.keymap().Press(keyStr); ok {
+			return b.runAction(action, count)
+		}
+	}
+	return nil
+}
+
+// jumpToSection scrolls the page to the heading at index i of the outline.
+func (b *Browser) jumpToSection(i int) {
+	tab := b.Tabs.ActiveTab()
+	if i < 0 || i >= len(tab.outline) {
+		return
+	}
+	b.outlineSelected = i
+	b.Viewport.SetYOffset(tab.outline[i].line)
+	tab.setScrollPos(b.Viewport.YOffset)
+}
+
+// currentSection returns the index of the last heading at or above the top
+// of the viewport, or -1 before the first one.
+func (b *Browser) currentSection() int {
+	current := -1
+	for i, entry := range b.Tabs.ActiveTab().outline {
+		if entry.line > b.Viewport.YOffset {
+			break
+		}
+		current = i
+	}
+	return current
+}
+
+// outlineView draws the outline pane, height lines tall, scrolled to keep
+// the selected or current heading in view.
+func (b *Browser) outlineView(height int) string {
+	theme := config.GetTheme()
+	width := b.outlineWidth() - 2
+	outline := b.Tabs.ActiveTab().outline
+
+	pane := lipgloss.NewStyle().
+		Width(width+1).
+		Height(height).
+		MaxHeight(height).
+		PaddingLeft(1).
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(lipgloss.Color(theme.TabColor))
+
+	if len(outline) == 0 {
+		return pane.Faint(true).Render("No headings")
+	}
+
+	minLevel := outline[0].level
+	for _, entry := range outline {
+		minLevel = min(minLevel, entry.level)
+	}
+
+	current := b.currentSection()
+	focus := current
+	if b.ActivePane == ACTIVE_OUTLINE {
+		focus = b.outlineSelected
+	}
+	start := max(0, min(focus-height/3, len(outline)-height))
+
+	lines := make([]string, 0, height)
+	for i := start; i < len(outline) && len(lines) < height; i++ {
+		entry := outline[i]
+		text := strings.Repeat("  ", entry.level-minLevel) + dom.VisualOrder(entry.text)
+		text = truncate.StringWithTail(text, uint(width), "…")
+
+		style := lipgloss.NewStyle().Width(width)
+		if i == current {
+			style = style.Bold(true).Foreground(lipgloss.Color(theme.TabActiveColor))
+		}
+		if i == b.outlineSelected && b.ActivePane == ACTIVE_OUTLINE {
+			style = style.Background(lipgloss.Color(theme.TabActiveColor)).Foreground(lipgloss.Color(theme.TabActiveTextColor))
+		}
+		lines = append(lines, zone.Mark(fmt.Sprintf("%s%d", OUTLINE_ID, i), style.Render(text)))
+	}
+	return pane.Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"ruppi/pkg/fuzzy"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	maxPaletteItems   = 12
+	maxPaletteHistory = 50
+)
+
+// openPalette turns the URL bar into the command palette: a fuzzy finder
+// over every action, the open tabs and recent history, shown in the omnibox
+// dropdown.
+func (b *Browser) openPalette() tea.Cmd {
+	b.closeOmnibox()
+	b.ActivePane = ACTIVE_PALETTE
+	b.Url.Prompt = "» "
+	b.Url.Placeholder = "action, tab or page"
+	b.Url.SetValue("")
+	b.updatePalette()
+	return b.Url.Focus()
+}
+
+// paletteItems lists everything the palette can pick from: the actions with
+// their current keys, then the open tabs, then recently visited pages.
+func (b *Browser) paletteItems() []omniboxItem {
+	var items []omniboxItem
+	for _, info := range actions {
+		if info.action == ACTION_COMMAND_PALETTE {
+			continue
+		}
+		items = append(items, omniboxItem{
+			label:  "action",
+			title:  info.description,
+			tab:    -1,
+			action: info.action,
+			keys:   b.keymap().Binding(info.action).Help().Key,
+		})
+	}
+
+	for i, tab := range b.Tabs.Tabs {
+		if tab != b.Tabs.ActiveTab() && tab.url != "" {
+			items = append(items, omniboxItem{label: "tab", title: tab.Title(), url: tab.url, tab: i})
+		}
+	}
+
+	if b.History != nil {
+		recent := b.History.Recent()
+		for _, entry := range recent[:min(len(recent), maxPaletteHistory)] {
+			items = append(items, omniboxItem{label: "history", title: entry.Title, url: entry.URL, tab: -1})
+		}
+	}
+	return items
+}
+
+// updatePalette refreshes the palette for what has been typed, best match
+// first.
+func (b *Browser) updatePalette() {
+	b.omnibox = omnibox{selected: 0}
+
+	items := b.paletteItems()
+	query := strings.TrimSpace(b.Url.Value())
+	if query == "" {
+		b.omnibox.items = items[:min(len(items), maxPaletteItems)]
+		return
+	}
+
+	texts := make([]string, len(items))
+	for i, item := range items {
+		texts[i] = item.label + " " + item.title + " " + string(item.action) + " " + item.url
+	}
+	for _, m := range fuzzy.Find(query, texts) {
+		if len(b.omnibox.items) >= maxPaletteItems {
+			break
+		}
+		b.omnibox.items = append(b.omnibox.items, items[m.Index])
+	}
+}
+
+// runPaletteItem closes the palette and does what the highlighted item, or
+// the best match when none is, stands for.
+func (b *Browser) runPaletteItem() tea.Cmd {
+	item := b.selectedOmniboxItem()
+	if item == nil && len(b.omnibox.items) > 0 {
+		item = &b.omnibox.items[0]
+	}
+	if item == nil {
+		b.endPrompt()
+		return nil
+	}
+
+	picked := *item
+	b.endPrompt()
+
+	switch {
+	case picked.action != "":
+		return runActionCmd(picked.action, 0)
+	case picked.tab >= 0:
+		index := b.Tabs.scrollTo(picked.tab)
+		return createChangeTabCmd(index - b.Tabs.visibleTabStartIndex)
+	default:
+		return navigateCmd(picked.url)
+	}
+}
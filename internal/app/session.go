@@ -0,0 +1,144 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"ruppi/internal/config"
+	"sort"
+	"strings"
+	"time"
+)
+
+const DEFAULT_SESSION = "default"
+
+// Session is the saved state of every open tab.
+type Session struct {
+	Tabs                 []SessionTab `json:"tabs"`
+	ActiveTab            int          `json:"active_tab"`
+	VisibleTabStartIndex int          `json:"visible_tab_start_index"`
+	SavedAt              time.Time    `json:"saved_at"`
+}
+
+// SessionTab is the saved state of a single tab.
+type SessionTab struct {
+	URL       string   `json:"url"`
+	Title     string   `json:"title"`
+	ScrollPos int      `json:"scroll_pos"`
+	Back      []string `json:"back,omitempty"`
+	Forward   []string `json:"forward,omitempty"`
+}
+
+// sessionPath returns the file a named session is stored in.
+func sessionPath(name string) string {
+	return filepath.Join(config.GetDataDir(), "sessions", name+".json")
+}
+
+// validSessionName keeps session names usable as file names.
+func validSessionName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid session name: %q", name)
+	}
+	return nil
+}
+
+// Snapshot returns the current state of the tabs.
+func (ts *Tabs) Snapshot() Session {
+	session := Session{
+		ActiveTab:            ts.activeTabID,
+		VisibleTabStartIndex: ts.visibleTabStartIndex,
+		SavedAt:              time.Now(),
+	}
+
+	for _, tab := range ts.Tabs {
+		session.Tabs = append(session.Tabs, SessionTab{
+			URL:       tab.url,
+			Title:     tab.title,
+			ScrollPos: tab.scrollPos,
+			Back:      tab.back,
+			Forward:   tab.forward,
+		})
+	}
+
+	return session
+}
+
+// Restore replaces the open tabs with the ones in session. Only the active
+// tab is loaded; the others load when they are switched to.
+func (ts *Tabs) Restore(session Session, wordWrap int, isKitty bool) {
+	if len(session.Tabs) == 0 {
+		return
+	}
+
+	ts.Tabs = make([]*Tab, 0, len(session.Tabs))
+	for i, saved := range session.Tabs {
+		ts.Tabs = append(ts.Tabs, &Tab{
+			id:        i,
+			url:       saved.URL,
+			title:     saved.Title,
+			scrollPos: saved.ScrollPos,
+			back:      saved.Back,
+			forward:   saved.Forward,
+			history:   ts.History,
+		})
+	}
+	ts.TotalTabCount = len(ts.Tabs) - 1
+
+	ts.visibleTabStartIndex = max(0, session.VisibleTabStartIndex)
+	ts.SwitchTo(session.ActiveTab, wordWrap, isKitty)
+}
+
+// SaveSession writes the tabs to the session called name.
+func SaveSession(name string, ts *Tabs) error {
+	if err := validSessionName(name); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(ts.Snapshot(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode session: %w", err)
+	}
+
+	path := sessionPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("could not create session directory: %w", err)
+	}
+
+	// write to a temporary file first so a crash can't leave half a session
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("could not write session: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadSession reads the session called name.
+func LoadSession(name string) (Session, error) {
+	if err := validSessionName(name); err != nil {
+		return Session{}, err
+	}
+
+	data, err := os.ReadFile(sessionPath(name))
+	if err != nil {
+		return Session{}, fmt.Errorf("could not read session: %w", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return Session{}, fmt.Errorf("could not decode session: %w", err)
+	}
+	return session, nil
+}
+
+// ListSessions returns the names of the saved sessions.
+func ListSessions() []string {
+	files, _ := filepath.Glob(filepath.Join(config.GetDataDir(), "sessions", "*.json"))
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(file), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
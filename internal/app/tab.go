@@ -2,12 +2,15 @@ package app
 
 import (
 	"fmt"
+	neturl "net/url"
 	"ruppi/internal/config"
 	"ruppi/internal/dom"
+	"ruppi/internal/history"
 	"ruppi/pkg/helper"
 	"ruppi/pkg/httpclient"
 	"ruppi/pkg/style"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	zone "github.com/lrstanley/bubblezone"
@@ -27,31 +30,302 @@ type Tab struct {
 	id            int
 	document      dom.Node
 	rendered      string
+	wrapped       string
+	images        []*dom.InlineImage
+	anchors       map[string]int
+	outline       []outlineEntry
 	title         string
 	scrollPos     int
 	renderedWidth int
 	url           string
+
+	// back and forward are the history stacks of the tab, most recent last.
+	back    []string
+	forward []string
+
+	// loaded is false for tabs restored from a session that haven't been
+	// shown yet, so restoring doesn't fetch every page up front.
+	loaded bool
+
+	// reader is set while the page is shown in reader mode, as article.
+	reader  bool
+	article *dom.Node
+	// readerChosen is set when reader mode was turned on in the tab, which
+	// keeps it on for the pages loaded next.
+	readerChosen bool
+
+	// details are the <details> of the page and their summary lines.
+	details []detailsEntry
+
+	// history is where the pages loaded in the tab are recorded, if
+	// anywhere.
+	history *history.Store
+}
+
+type detailsEntry struct {
+	details dom.Details
+	line    int
 }
 
 func (t *Tab) Render(wordwrap int, isKitty bool) {
-	t.rendered = dom.WordWrap(t.document.Render(t.url, isKitty), wordwrap)
+	document := &t.document
+	if t.reader && t.article != nil {
+		document = t.article
+	}
+
+	page := document.Render(t.url, wordwrap, isKitty)
+	var lines []int
+	t.wrapped, lines = dom.LocateAnchors(dom.WordWrap(page.Content, wordwrap), len(page.Anchors))
+	t.locateAnchors(page, lines)
+	t.images = page.Images
+	dom.LocateImages(t.wrapped, t.images)
+	t.rendered = dom.ExpandImages(t.wrapped, t.images)
 	t.renderedWidth = wordwrap
 }
 
+// IsAnimated reports whether the tab has images to play.
+func (t *Tab) IsAnimated() bool {
+	for _, img := range t.images {
+		if img.IsAnimated() {
+			return true
+		}
+	}
+	return false
+}
+
+// Animate advances the animated images visible between the lines top and
+// top+height. Images scrolled out of view stay paused. It reports whether the
+// rendered content changed.
+func (t *Tab) Animate(elapsed time.Duration, top, height int) bool {
+	changed := false
+	for _, img := range t.images {
+		if img.Visible(top, height) && img.Advance(elapsed) {
+			changed = true
+		}
+	}
+
+	if changed {
+		t.rendered = dom.ExpandImages(t.wrapped, t.images)
+	}
+	return changed
+}
+
+// VisibleImages returns the images with a row between the lines top and
+// top+height.
+func (t *Tab) VisibleImages(top, height int) []*dom.InlineImage {
+	var visible []*dom.InlineImage
+	for _, img := range t.images {
+		if img.Visible(top, height) {
+			visible = append(visible, img)
+		}
+	}
+	return visible
+}
+
+// ShowImageHints draws the given labels in place of their images. Passing
+// nil brings the images back.
+func (t *Tab) ShowImageHints(labels map[*dom.InlineImage]string) {
+	t.rendered = dom.ExpandImageLabels(t.wrapped, t.images, labels)
+}
+
 func (t *Tab) setScrollPos(pos int) {
 	t.scrollPos = pos
 }
 
-func (t *Tab) ChangeURL(url string, wordWrap int, isKitty bool) {
-	documentNode, title, err := httpclient.GetUrlAsNode(url)
+func (t *Tab) URL() string {
+	return t.url
+}
+
+func (t *Tab) Title() string {
+	return t.title
+}
+
+// load fetches url and renders it, without touching the tab's back and
+// forward history.
+func (t *Tab) load(url string, wordWrap int, isKitty bool) {
+	if t.isSamePage(url) {
+		t.url = url
+		t.scrollPos = t.anchorLine(url)
+		return
+	}
+
+	var documentNode dom.Node
+	var title string
+	var err error
+
+	if url == "" {
+		documentNode, title, err = httpclient.DefaultPage()
+	} else {
+		documentNode, title, err = httpclient.GetUrlAsNode(url)
+	}
+
 	if err != nil {
 		documentNode, title, _ = httpclient.ErrorPage(err)
+	} else if url != "" && !httpclient.IsAboutURL(url) && t.history != nil {
+		t.history.Record(url, title)
 	}
+
 	t.document = documentNode
 	t.title = title
 	t.url = url
+	t.loaded = true
+	t.article = nil
+	t.setReader(err == nil && (t.readerChosen || config.UseReaderMode(url)))
 
 	t.Render(wordWrap, isKitty)
+	t.scrollPos = t.anchorLine(url)
+}
+
+// locateAnchors keeps the line of every named anchor, the first one winning
+// as in a browser, and of every heading of the outline.
+func (t *Tab) locateAnchors(page dom.Page, lines []int) {
+	t.anchors = map[string]int{}
+	for i, name := range page.Anchors {
+		if _, seen := t.anchors[name]; name != "" && !seen && lines[i] >= 0 {
+			t.anchors[name] = lines[i]
+		}
+	}
+
+	t.outline = nil
+	for _, heading := range page.Headings {
+		if line := lines[heading.Anchor]; line >= 0 {
+			t.outline = append(t.outline, outlineEntry{level: heading.Level, text: heading.Text, line: line})
+		}
+	}
+
+	t.details = nil
+	for _, details := range page.Details {
+		if line := lines[details.Anchor]; line >= 0 {
+			t.details = append(t.details, detailsEntry{details: details, line: line})
+		}
+	}
+}
+
+// detailsAt returns the index of the <details> whose summary is on line, or
+// -1.
+func (t *Tab) detailsAt(line int) int {
+	for i, entry := range t.details {
+		if entry.line == line {
+			return i
+		}
+	}
+	return -1
+}
+
+// detailsInView returns the indexes of the <details> with their summary
+// between the lines top and top+height. When there is none, it returns the
+// last one above top, whose content may be what is in view.
+func (t *Tab) detailsInView(top, height int) []int {
+	var inView []int
+	above := -1
+	for i, entry := range t.details {
+		if entry.line >= top+height {
+			break
+		}
+		if entry.line >= top {
+			inView = append(inView, i)
+		} else {
+			above = i
+		}
+	}
+	if len(inView) == 0 && above >= 0 {
+		return []int{above}
+	}
+	return inView
+}
+
+// ShowDetailsHints draws the given labels in place of the open or closed
+// markers of the <details> at their indexes. Passing nil brings the markers
+// back.
+func (t *Tab) ShowDetailsHints(labels map[int]string) {
+	t.rendered = dom.ExpandImages(t.wrapped, t.images)
+	if len(labels) == 0 {
+		return
+	}
+
+	lines := strings.Split(t.rendered, "\n")
+	for i, label := range labels {
+		if line := t.details[i].line; line < len(lines) {
+			lines[line] = dom.LabelDetails(lines[line], label)
+		}
+	}
+	t.rendered = strings.Join(lines, "\n")
+}
+
+// setReader turns reader mode for the page on or off. It stays off when the
+// page has no article.
+func (t *Tab) setReader(on bool) {
+	if on && t.article == nil {
+		if article, ok := dom.Readable(t.document, t.title); ok {
+			t.article = &article
+		} else {
+			on = false
+		}
+	}
+	t.reader = on
+}
+
+// isSamePage reports whether url is the page the tab shows with another
+// fragment, which only needs scrolling to.
+func (t *Tab) isSamePage(url string) bool {
+	if !t.loaded || t.url == "" || url == t.url {
+		return false
+	}
+	page, fragment, _ := strings.Cut(url, "#")
+	current, currentFragment, _ := strings.Cut(t.url, "#")
+	return page == current && (fragment != "" || currentFragment != "")
+}
+
+// anchorLine returns the line of the element the fragment of url points at,
+// or 0 when there is none.
+func (t *Tab) anchorLine(url string) int {
+	_, fragment, ok := strings.Cut(url, "#")
+	if !ok || fragment == "" {
+		return 0
+	}
+	if line, ok := t.anchors[fragment]; ok {
+		return line
+	}
+	if decoded, err := neturl.PathUnescape(fragment); err == nil {
+		return t.anchors[decoded]
+	}
+	return 0
+}
+
+func (t *Tab) ChangeURL(url string, wordWrap int, isKitty bool) {
+	if t.loaded {
+		t.back = append(t.back, t.url)
+		t.forward = nil
+	}
+	t.load(url, wordWrap, isKitty)
+}
+
+// Back loads the previous page of the tab's history. It reports false if
+// there is none.
+func (t *Tab) Back(wordWrap int, isKitty bool) bool {
+	if len(t.back) == 0 {
+		return false
+	}
+
+	url := t.back[len(t.back)-1]
+	t.back = t.back[:len(t.back)-1]
+	t.forward = append(t.forward, t.url)
+	t.load(url, wordWrap, isKitty)
+	return true
+}
+
+// Forward loads the next page of the tab's history, undoing Back. It reports
+// false if there is none.
+func (t *Tab) Forward(wordWrap int, isKitty bool) bool {
+	if len(t.forward) == 0 {
+		return false
+	}
+
+	url := t.forward[len(t.forward)-1]
+	t.forward = t.forward[:len(t.forward)-1]
+	t.back = append(t.back, t.url)
+	t.load(url, wordWrap, isKitty)
+	return true
 }
 
 type Tabs struct {
@@ -60,6 +334,10 @@ type Tabs struct {
 	activeTab            *Tab
 	activeTabID          int
 	visibleTabStartIndex int
+
+	// History is where the pages loaded in the tabs are recorded, if
+	// anywhere.
+	History *history.Store
 }
 
 func (ts *Tabs) Render(wordWrap int, isKitty bool) {
@@ -89,6 +367,17 @@ func (ts *Tabs) ChangeActiveTabURL(url string, wordWrap int, isKitty bool) {
 	}
 }
 
+// tabTitle fits title in width columns after prefix. A right-to-left title
+// is cut in the order it is read in and reordered after, so it keeps its
+// start rather than its end.
+func tabTitle(prefix, title string, width int) string {
+	cut := helper.TruncateString(prefix+title, width, true)
+	if rest, ok := strings.CutPrefix(cut, prefix); ok {
+		cut = prefix + dom.VisualOrder(strings.TrimRight(rest, " "))
+	}
+	return helper.TruncateString(cut, width, false)
+}
+
 func (ts *Tabs) ShowTabs(width int) string {
 	tab_str := strings.Builder{}
 	tabContainerWidth := width - REMOVE_EXTRA_TAB_BUTTONS
@@ -110,12 +399,12 @@ func (ts *Tabs) ShowTabs(width int) string {
 		}
 
 		theme := config.GetTheme()
-		title := tab.title
+		prefix := ""
 
 		// Use theme colors for tab styling
 		var tabStyle lipgloss.Style
 		if ts.activeTabID == tab.id {
-			title = fmt.Sprintf("%s%s", "🐦 ", tab.title)
+			prefix = "🐦 "
 			tabStyle = lipgloss.NewStyle().
 				Background(lipgloss.Color(theme.TabActiveColor)).
 				Foreground(lipgloss.Color(theme.TabActiveTextColor)).
@@ -135,7 +424,7 @@ func (ts *Tabs) ShowTabs(width int) string {
 
 		tabContent := zone.Mark(fmt.Sprintf("%s%d", TAB_ID, k),
 			string(tabPrefixNumber[k])+" "+
-				helper.TruncateString(title, tabsWidth-6, true)+" "+theme.TabCloseIcon)
+				tabTitle(prefix, tab.title, tabsWidth-6)+" "+theme.TabCloseIcon)
 
 		tab_str.WriteString(tabStyle.Render(tabContent))
 		k += 1
@@ -194,35 +483,42 @@ func (ts *Tabs) MoveRight() {
 	}
 }
 
-func (ts *Tabs) ChangeTab(id int) {
+func (ts *Tabs) ChangeTab(id int, wordWrap int, isKitty bool) {
 	ts.activeTab = ts.Tabs[ts.visibleTabStartIndex+id]
 	ts.activeTabID = ts.visibleTabStartIndex + id
-}
 
-func (ts *Tabs) NewTab(url string, wordWrap int, isKitty bool) {
-	var documentNode dom.Node
-	var title string
-	var err error
-
-	if url == "" {
-		documentNode, title, err = httpclient.DefaultPage()
-	} else {
-		documentNode, title, err = httpclient.GetUrlAsNode(url)
+	// tabs are only rendered while active, so catch up on restores and resizes
+	if !ts.activeTab.loaded {
+		scrollPos := ts.activeTab.scrollPos
+		ts.activeTab.load(ts.activeTab.url, wordWrap, isKitty)
+		ts.activeTab.scrollPos = scrollPos
+	} else if ts.activeTab.renderedWidth != wordWrap {
+		ts.activeTab.Render(wordWrap, isKitty)
 	}
+}
 
-	if err != nil {
-		documentNode, title, _ = httpclient.ErrorPage(err)
-	}
+// SwitchTo makes the tab at index active, scrolling the tab bar to it.
+func (ts *Tabs) SwitchTo(index int, wordWrap int, isKitty bool) {
+	index = ts.scrollTo(index)
+	ts.ChangeTab(index-ts.visibleTabStartIndex, wordWrap, isKitty)
+}
 
-	tab := &Tab{
-		id:        len(ts.Tabs),
-		document:  documentNode,
-		title:     title,
-		scrollPos: 0,
-		url:       url,
+// scrollTo scrolls the tab bar so the tab at index is visible and returns
+// the index clamped to the open tabs.
+func (ts *Tabs) scrollTo(index int) int {
+	index = max(0, min(index, len(ts.Tabs)-1))
+	if index < ts.visibleTabStartIndex {
+		ts.visibleTabStartIndex = index
+	} else if index-ts.visibleTabStartIndex >= MAX_TABS_IN_PAGE {
+		ts.visibleTabStartIndex = index - MAX_TABS_IN_PAGE + 1
 	}
+	return index
+}
+
+func (ts *Tabs) NewTab(url string, wordWrap int, isKitty bool) {
+	tab := &Tab{id: len(ts.Tabs), history: ts.History}
+	tab.load(url, wordWrap, isKitty)
 
-	tab.Render(wordWrap, isKitty)
 	ts.TotalTabCount = len(ts.Tabs)
 
 	ts.Tabs = append(ts.Tabs, tab)
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"ruppi/internal/config"
+	"ruppi/internal/dom"
+	"ruppi/pkg/httpclient"
+	"strings"
+	"testing"
+
+	"github.com/mattn/go-runewidth"
+)
+
+func TestTabTitle(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		title  string
+		width  int
+		want   string
+	}{
+		{"fits", "", "Go", 6, "Go    "},
+		{"cut", "", "The Go Programming Language", 10, "The Go ..."},
+		{"prefix", "🐦 ", "Go", 6, "🐦 Go "},
+		// "שלום עולם גדול" keeps "שלום" (start), shown reversed, not "גדול"
+		{"right to left keeps its start", "", "שלום עולם גדול", 10, "...וע םולש"},
+		{"right to left after prefix", "🐦 ", "שלום", 8, "🐦 םולש "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tabTitle(tt.prefix, tt.title, tt.width)
+			if got != tt.want {
+				t.Errorf("tabTitle(%q, %q, %d) = %q, want %q", tt.prefix, tt.title, tt.width, got, tt.want)
+			}
+			if w := runewidth.StringWidth(got); w != tt.width {
+				t.Errorf("width %d, want %d", w, tt.width)
+			}
+			if strings.Contains(got, "לודג") {
+				t.Error("kept the end of the title")
+			}
+		})
+	}
+}
+
+func TestDetailsInView(t *testing.T) {
+	tab := &Tab{details: []detailsEntry{{line: 2}, {line: 10}, {line: 12}, {line: 30}}}
+	tests := []struct {
+		name        string
+		top, height int
+		want        []int
+	}{
+		{"all in view", 0, 40, []int{0, 1, 2, 3}},
+		{"some in view", 5, 10, []int{1, 2}},
+		{"last one above", 14, 10, []int{2}},
+		{"none above", 0, 2, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tab.detailsInView(tt.top, tt.height)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("detailsInView(%d, %d) = %v, want %v", tt.top, tt.height, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShowDetailsHints(t *testing.T) {
+	tab := &Tab{
+		wrapped: "intro\n▸ One\n▾ Two\n  body",
+		details: []detailsEntry{{line: 1}, {line: 2}},
+	}
+
+	tab.ShowDetailsHints(map[int]string{0: "1", 1: "2"})
+	if want := "intro\n1 One\n2 Two\n  body"; tab.rendered != want {
+		t.Errorf("with hints got %q, want %q", tab.rendered, want)
+	}
+
+	tab.ShowDetailsHints(nil)
+	if tab.rendered != tab.wrapped {
+		t.Errorf("without hints got %q, want %q", tab.rendered, tab.wrapped)
+	}
+}
+
+// TestRenderedFootnotes renders a page the way -raw and -o do, through a new
+// tab, with links shown as footnotes.
+func TestRenderedFootnotes(t *testing.T) {
+	if err := config.Set("link-display", config.LINK_DISPLAY_FOOTNOTE); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.Set("link-display", config.LINK_DISPLAY_INLINE) })
+	httpclient.RegisterAboutPage("footnotes-test", func(*url.URL) (string, error) {
+		return `<p><a href="https://go.dev/">Go</a> and <a href="https://go.dev/">Go again</a></p>`, nil
+	})
+
+	tabs := &Tabs{Tabs: []*Tab{}}
+	tabs.NewTab("about:footnotes-test", 80, false)
+	rendered := tabs.Rendered()
+
+	// FindText matches across the escape codes of the styles
+	for _, want := range []string{"Go[1]", "Go again[1]", "References", "1. https://go.dev/"} {
+		if len(dom.FindText(rendered, want)) == 0 {
+			t.Errorf("rendered page is missing %q:\n%s", want, rendered)
+		}
+	}
+	if matches := dom.FindText(rendered, "https://go.dev/"); len(matches) != 1 {
+		t.Errorf("the URL is listed %d times, want once", len(matches))
+	}
+}
@@ -0,0 +1,211 @@
+// Package bookmarks keeps the user's saved pages in a JSON file.
+package bookmarks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"ruppi/pkg/fuzzy"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Bookmark is a saved page. Folder is a slash separated path such as
+// "Dev/Go"; an empty folder is the top level.
+type Bookmark struct {
+	URL    string    `json:"url"`
+	Title  string    `json:"title"`
+	Folder string    `json:"folder,omitempty"`
+	Tags   []string  `json:"tags,omitempty"`
+	Added  time.Time `json:"added"`
+}
+
+// Store holds the bookmarks and the file they are saved to.
+type Store struct {
+	path      string
+	Bookmarks []Bookmark `json:"bookmarks"`
+
+	// loadErr is why the file couldn't be loaded while it is still in
+	// place. The store isn't saved over it then, so it isn't lost.
+	loadErr error
+}
+
+// Load reads the bookmarks saved at path. A missing file gives an empty store
+// that will be created on the first save. A file that can't be decoded is
+// moved aside to path.bak and the store starts empty; one that can't be read
+// is left alone, and the store won't save over it.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		s.loadErr = fmt.Errorf("could not read bookmarks: %w", err)
+		return s, s.loadErr
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		// start over, keeping the file that couldn't be decoded aside
+		s.Bookmarks = nil
+		backup := path + ".bak"
+		if renameErr := os.Rename(path, backup); renameErr != nil {
+			s.loadErr = fmt.Errorf("could not decode bookmarks: %w", err)
+			return s, s.loadErr
+		}
+		return s, fmt.Errorf("could not decode bookmarks, moved it to %s: %w", backup, err)
+	}
+	return s, nil
+}
+
+// Save writes the bookmarks back to their file.
+func (s *Store) Save() error {
+	if s.loadErr != nil {
+		return fmt.Errorf("not saving over the bookmarks that couldn't be loaded: %w", s.loadErr)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode bookmarks: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("could not create bookmarks directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("could not write bookmarks: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Add saves a bookmark, replacing any existing one for the same URL. A
+// replaced bookmark keeps the time it was first added.
+func (s *Store) Add(b Bookmark) error {
+	if b.Added.IsZero() {
+		b.Added = time.Now()
+	}
+	b.Folder = CleanFolder(b.Folder)
+
+	if i := s.index(b.URL); i >= 0 {
+		if added := s.Bookmarks[i].Added; !added.IsZero() {
+			b.Added = added
+		}
+		s.Bookmarks[i] = b
+	} else {
+		s.Bookmarks = append(s.Bookmarks, b)
+	}
+	return s.Save()
+}
+
+// Remove deletes the bookmark for url, if there is one.
+func (s *Store) Remove(url string) error {
+	i := s.index(url)
+	if i < 0 {
+		return nil
+	}
+
+	s.Bookmarks = append(s.Bookmarks[:i], s.Bookmarks[i+1:]...)
+	return s.Save()
+}
+
+// Get returns the bookmark for url.
+func (s *Store) Get(url string) (Bookmark, bool) {
+	if i := s.index(url); i >= 0 {
+		return s.Bookmarks[i], true
+	}
+	return Bookmark{}, false
+}
+
+func (s *Store) index(url string) int {
+	for i, b := range s.Bookmarks {
+		if b.URL == url {
+			return i
+		}
+	}
+	return -1
+}
+
+// Search returns the bookmarks fuzzy matching query on their title, URL,
+// folder and tags, best match first.
+func (s *Store) Search(query string) []Bookmark {
+	items := make([]string, len(s.Bookmarks))
+	for i, b := range s.Bookmarks {
+		items[i] = b.searchText()
+	}
+
+	var results []Bookmark
+	for _, m := range fuzzy.Find(query, items) {
+		results = append(results, s.Bookmarks[m.Index])
+	}
+	return results
+}
+
+func (b Bookmark) searchText() string {
+	text := b.Title + " " + b.URL
+	if b.Folder != "" {
+		text += " " + b.Folder
+	}
+	for _, tag := range b.Tags {
+		text += " #" + tag
+	}
+	return text
+}
+
+// Folders returns every folder in use, sorted.
+func (s *Store) Folders() []string {
+	seen := map[string]bool{}
+	var folders []string
+	for _, b := range s.Bookmarks {
+		if !seen[b.Folder] {
+			seen[b.Folder] = true
+			folders = append(folders, b.Folder)
+		}
+	}
+	sort.Strings(folders)
+	return folders
+}
+
+// InFolder returns the bookmarks directly in folder, oldest first.
+func (s *Store) InFolder(folder string) []Bookmark {
+	var results []Bookmark
+	for _, b := range s.Bookmarks {
+		if b.Folder == folder {
+			results = append(results, b)
+		}
+	}
+	return results
+}
+
+// CleanFolder normalises a folder path: no empty parts or stray slashes.
+func CleanFolder(folder string) string {
+	var parts []string
+	for _, part := range strings.Split(folder, "/") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// ParseLabels splits the text typed when bookmarking, like
+// "Dev/Go #modules #docs", into a folder and tags.
+func ParseLabels(text string) (string, []string) {
+	var folder []string
+	var tags []string
+	for _, field := range strings.Fields(text) {
+		if tag, ok := strings.CutPrefix(field, "#"); ok {
+			if tag != "" {
+				tags = append(tags, tag)
+			}
+		} else {
+			folder = append(folder, field)
+		}
+	}
+	return CleanFolder(strings.Join(folder, " ")), tags
+}
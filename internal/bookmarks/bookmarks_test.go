@@ -0,0 +1,74 @@
+package bookmarks
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAddKeepsAddedTime(t *testing.T) {
+	s, err := Load(filepath.Join(t.TempDir(), "bookmarks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := s.Add(Bookmark{URL: "https://go.dev/", Title: "Go", Added: first}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(Bookmark{URL: "https://go.dev/", Title: "The Go language", Folder: "dev"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := s.Get("https://go.dev/")
+	if !ok {
+		t.Fatal("bookmark is gone")
+	}
+	if got.Title != "The Go language" || got.Folder != "dev" {
+		t.Errorf("bookmark wasn't replaced: %+v", got)
+	}
+	if !got.Added.Equal(first) {
+		t.Errorf("Added = %v, want %v", got.Added, first)
+	}
+	if len(s.Bookmarks) != 1 {
+		t.Errorf("%d bookmarks, want 1", len(s.Bookmarks))
+	}
+}
+
+func TestLoadCorruptFileIsKept(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	corrupt := []byte(`{"bookmarks": [{"url": "https://go.dev/"`)
+	if err := os.WriteFile(path, corrupt, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Load(path)
+	if err == nil {
+		t.Fatal("Load of a corrupt file didn't fail")
+	}
+	if len(s.Bookmarks) != 0 {
+		t.Errorf("got %d bookmarks from a corrupt file", len(s.Bookmarks))
+	}
+	if err := s.Add(Bookmark{URL: "https://example.com/"}); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := os.ReadFile(path + ".bak")
+	if err != nil || !bytes.Equal(backup, corrupt) {
+		t.Errorf("corrupt file wasn't moved aside: %q, %v", backup, err)
+	}
+}
+
+func TestUnreadableFileIsNotOverwritten(t *testing.T) {
+	// a directory can't be read as a file
+	path := t.TempDir()
+	s, err := Load(path)
+	if err == nil {
+		t.Fatal("Load of an unreadable file didn't fail")
+	}
+	if err := s.Add(Bookmark{URL: "https://example.com/"}); err == nil {
+		t.Error("saved over a file that couldn't be read")
+	}
+}
@@ -0,0 +1,166 @@
+package bookmarks
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// ImportNetscape adds the bookmarks from a Netscape bookmark file, the format
+// every browser exports to. Folders become folder paths and the TAGS
+// attribute becomes tags. It returns how many bookmarks were imported.
+func (s *Store) ImportNetscape(r io.Reader) (int, error) {
+	tokenizer := xhtml.NewTokenizer(r)
+
+	var (
+		folders       []string
+		pendingFolder string
+		current       *Bookmark
+		title         strings.Builder
+		inFolderTitle bool
+		imported      int
+	)
+
+	for {
+		switch tokenizer.Next() {
+		case xhtml.ErrorToken:
+			if tokenizer.Err() == io.EOF {
+				if imported > 0 {
+					return imported, s.Save()
+				}
+				return 0, nil
+			}
+			return imported, tokenizer.Err()
+
+		case xhtml.StartTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "h3":
+				inFolderTitle = true
+				title.Reset()
+			case "dl":
+				// a list belongs to the folder heading before it; the
+				// root list has none
+				folders = append(folders, pendingFolder)
+				pendingFolder = ""
+			case "a":
+				current = &Bookmark{}
+				title.Reset()
+				for _, attr := range token.Attr {
+					switch strings.ToLower(attr.Key) {
+					case "href":
+						current.URL = attr.Val
+					case "add_date":
+						if secs, err := strconv.ParseInt(attr.Val, 10, 64); err == nil {
+							current.Added = time.Unix(secs, 0)
+						}
+					case "tags":
+						for _, tag := range strings.Split(attr.Val, ",") {
+							if tag = strings.TrimSpace(tag); tag != "" {
+								current.Tags = append(current.Tags, tag)
+							}
+						}
+					}
+				}
+			}
+
+		case xhtml.TextToken:
+			if inFolderTitle || current != nil {
+				title.Write(tokenizer.Text())
+			}
+
+		case xhtml.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "h3":
+				inFolderTitle = false
+				pendingFolder = strings.ReplaceAll(strings.TrimSpace(title.String()), "/", "-")
+			case "dl":
+				if len(folders) > 0 {
+					folders = folders[:len(folders)-1]
+				}
+			case "a":
+				if current != nil && current.URL != "" {
+					current.Title = strings.TrimSpace(title.String())
+					current.Folder = CleanFolder(strings.Join(folders, "/"))
+					if current.Added.IsZero() {
+						current.Added = time.Now()
+					}
+
+					if i := s.index(current.URL); i >= 0 {
+						s.Bookmarks[i] = *current
+					} else {
+						s.Bookmarks = append(s.Bookmarks, *current)
+					}
+					imported++
+				}
+				current = nil
+			}
+		}
+	}
+}
+
+// ExportNetscape writes every bookmark as a Netscape bookmark file that other
+// browsers can import.
+func (s *Store) ExportNetscape(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE NETSCAPE-Bookmark-file-1>\n")
+	sb.WriteString(`<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">` + "\n")
+	sb.WriteString("<TITLE>Bookmarks</TITLE>\n<H1>Bookmarks</H1>\n<DL><p>\n")
+	s.writeFolder(&sb, "", 1)
+	sb.WriteString("</DL><p>\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeFolder writes the bookmarks of folder followed by its sub folders.
+func (s *Store) writeFolder(sb *strings.Builder, folder string, depth int) {
+	indent := strings.Repeat("    ", depth)
+
+	for _, b := range s.InFolder(folder) {
+		fmt.Fprintf(sb, `%s<DT><A HREF="%s" ADD_DATE="%d"`, indent, html.EscapeString(b.URL), b.Added.Unix())
+		if len(b.Tags) > 0 {
+			fmt.Fprintf(sb, ` TAGS="%s"`, html.EscapeString(strings.Join(b.Tags, ",")))
+		}
+		fmt.Fprintf(sb, ">%s</A>\n", html.EscapeString(b.Title))
+	}
+
+	for _, child := range s.subFolders(folder) {
+		name := child[strings.LastIndex(child, "/")+1:]
+		fmt.Fprintf(sb, "%s<DT><H3>%s</H3>\n%s<DL><p>\n", indent, html.EscapeString(name), indent)
+		s.writeFolder(sb, child, depth+1)
+		fmt.Fprintf(sb, "%s</DL><p>\n", indent)
+	}
+}
+
+// subFolders returns the folders directly below folder, including ones that
+// only exist as the parent of a deeper folder.
+func (s *Store) subFolders(folder string) []string {
+	prefix := ""
+	if folder != "" {
+		prefix = folder + "/"
+	}
+
+	seen := map[string]bool{}
+	var children []string
+	for _, f := range s.Folders() {
+		rest, ok := strings.CutPrefix(f, prefix)
+		if !ok || rest == "" || f == folder {
+			continue
+		}
+		child := prefix + strings.SplitN(rest, "/", 2)[0]
+		if !seen[child] {
+			seen[child] = true
+			children = append(children, child)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -42,11 +43,24 @@ type Theme struct {
 	InspectorToggleKey string
 	QuitKey            string
 	SearchKey          string
+	ImageViewerKey     string
+	BackKey            string
+	ForwardKey         string
+	BookmarkKey        string
 	NewTabTooltip      string
 
 	// Browser
 	BrowserBackground   string
 	InspectorBackground string
+
+	// Syntax highlighting of code blocks
+	SyntaxKeyword  string
+	SyntaxString   string
+	SyntaxNumber   string
+	SyntaxComment  string
+	SyntaxFunction string
+	SyntaxType     string
+	SyntaxOperator string
 }
 
 // SixelConfig holds sixel image settings
@@ -54,13 +68,51 @@ type SixelConfig struct {
 	Enabled   bool
 	MaxWidth  int
 	MaxHeight int
+
+	// Animate plays animated images (GIFs) in place instead of showing
+	// only their first frame.
+	Animate bool
+
+	// CellWidth and CellHeight are the pixel size of a terminal cell, used
+	// to work out how many lines an inline image takes up.
+	CellWidth  int
+	CellHeight int
+
+	// Colors is the palette size images are quantized to, Dither is one of
+	// "none", "ordered" or "floyd-steinberg", and Workers is how many
+	// goroutines encode an image (0 uses every CPU).
+	Colors  int
+	Dither  string
+	Workers int
+}
+
+// SessionConfig holds session persistence settings
+type SessionConfig struct {
+	// Restore reopens the tabs of the last session on startup.
+	Restore bool
+	// SaveInterval is how often the session is saved while running, in
+	// seconds. 0 only saves on quit.
+	SaveInterval int
+}
+
+// ListConfig holds how lists are laid out.
+type ListConfig struct {
+	// Indent is how many columns each level of a list is indented by.
+	Indent int
+	// Bullets mark the items of unordered lists, one per level of nesting,
+	// starting over past the last one.
+	Bullets []string
 }
 
 var (
-	ruppiConfig  = make(StyleMap)
-	maxGaps      = 3
-	currentTheme = getDefaultTheme()
-	sixelConfig  = SixelConfig{Enabled: true, MaxWidth: 400, MaxHeight: 300}
+	ruppiConfig   = make(StyleMap)
+	maxGaps       = 3
+	downloadDir   = ""
+	dataDir       = ""
+	sessionConfig = SessionConfig{Restore: false, SaveInterval: 60}
+	listConfig    = ListConfig{Indent: 2, Bullets: []string{"•", "◦", "▪"}}
+	currentTheme  = getDefaultTheme()
+	sixelConfig   = SixelConfig{Enabled: true, MaxWidth: 400, MaxHeight: 300, Animate: true, CellWidth: 10, CellHeight: 20, Colors: 255, Dither: "none"}
 )
 
 // GetSixelConfig returns the sixel configuration
@@ -220,6 +272,22 @@ func parseRuppiSetting(line string) error {
 	case "inspector-foreground":
 		currentTheme.InspectorForeground = value
 
+	// Syntax highlighting
+	case "syntax-keyword":
+		currentTheme.SyntaxKeyword = value
+	case "syntax-string":
+		currentTheme.SyntaxString = value
+	case "syntax-number":
+		currentTheme.SyntaxNumber = value
+	case "syntax-comment":
+		currentTheme.SyntaxComment = value
+	case "syntax-function":
+		currentTheme.SyntaxFunction = value
+	case "syntax-type":
+		currentTheme.SyntaxType = value
+	case "syntax-operator":
+		currentTheme.SyntaxOperator = value
+
 	// Icons
 	case "tab-close-icon":
 		currentTheme.TabCloseIcon = value
@@ -241,8 +309,18 @@ func parseRuppiSetting(line string) error {
 		currentTheme.QuitKey = value
 	case "search-key":
 		currentTheme.SearchKey = value
+	case "image-viewer-key":
+		currentTheme.ImageViewerKey = value
+	case "back-key":
+		currentTheme.BackKey = value
+	case "forward-key":
+		currentTheme.ForwardKey = value
+	case "bookmark-key":
+		currentTheme.BookmarkKey = value
 	case "new-tab-tooltip":
 		currentTheme.NewTabTooltip = value
+	case "keys-preset":
+		return setKeyPreset(value)
 
 	// Sixel Configuration
 	case "enable-sixel":
@@ -255,6 +333,71 @@ func parseRuppiSetting(line string) error {
 		if h, err := strconv.Atoi(value); err == nil && h > 0 {
 			sixelConfig.MaxHeight = h
 		}
+	case "sixel-colors":
+		if c, err := strconv.Atoi(value); err == nil && c >= 2 && c <= 256 {
+			sixelConfig.Colors = c
+		} else {
+			return fmt.Errorf("invalid sixel-colors value: %s", value)
+		}
+	case "sixel-dither":
+		switch strings.ToLower(value) {
+		case "none", "ordered", "floyd-steinberg":
+			sixelConfig.Dither = strings.ToLower(value)
+		default:
+			return fmt.Errorf("invalid sixel-dither value: %s", value)
+		}
+	case "sixel-workers":
+		if w, err := strconv.Atoi(value); err == nil && w >= 0 {
+			sixelConfig.Workers = w
+		}
+	case "animate-images":
+		sixelConfig.Animate = parseBool(value)
+	case "cell-width":
+		if w, err := strconv.Atoi(value); err == nil && w > 0 {
+			sixelConfig.CellWidth = w
+		}
+	case "cell-height":
+		if h, err := strconv.Atoi(value); err == nil && h > 0 {
+			sixelConfig.CellHeight = h
+		}
+
+	// Downloads
+	case "download-dir":
+		downloadDir = value
+
+	// Sessions
+	case "data-dir":
+		dataDir = value
+	case "restore-session":
+		sessionConfig.Restore = parseBool(value)
+	case "session-save-interval":
+		if i, err := strconv.Atoi(value); err == nil && i >= 0 {
+			sessionConfig.SaveInterval = i
+		} else {
+			return fmt.Errorf("invalid session-save-interval value: %s", value)
+		}
+
+	// Lists
+	case "list-indent":
+		if i, err := strconv.Atoi(value); err == nil && i >= 0 && i <= 8 {
+			listConfig.Indent = i
+		} else {
+			return fmt.Errorf("invalid list-indent value: %s", value)
+		}
+	case "list-bullets":
+		if bullets := strings.Fields(value); len(bullets) > 0 {
+			listConfig.Bullets = bullets
+		} else {
+			return fmt.Errorf("invalid list-bullets value: %s", value)
+		}
+
+	// Links
+	case "link-display":
+		return setLinkDisplay(value)
+
+	// Reader mode
+	case "reader-mode-domains":
+		return setReaderDomains(value)
 
 	default:
 		return fmt.Errorf("unknown ruppi setting: %s", key)
@@ -263,6 +406,12 @@ func parseRuppiSetting(line string) error {
 	return nil
 }
 
+// Set changes a [ruppi] setting while Ruppi is running, as if it had been
+// written in the config file.
+func Set(key, value string) error {
+	return parseRuppiSetting(key + " " + value)
+}
+
 func parseBool(s string) bool {
 	b, _ := strconv.ParseBool(s)
 	return b
@@ -302,6 +451,46 @@ func AddStyle(tag string, content string) string {
 	return content
 }
 
+// AddBlockStyle styles the content of a block like AddStyle, but leaves
+// out the vertical margins of the style and returns them apart, so they
+// collapse with the margins around the block.
+func AddBlockStyle(tag string, content string) (string, int, int) {
+	val, has := ruppiConfig[tag]
+	if !has {
+		return content, 0, 0
+	}
+
+	top, bottom := val.Style.GetMarginTop(), val.Style.GetMarginBottom()
+	if strings.TrimSpace(content) == "" {
+		return content, top, bottom
+	}
+	return val.Style.UnsetMarginTop().UnsetMarginBottom().Render(val.Prefix + content + val.Infix), top, bottom
+}
+
+// FrameWidth is how many columns the prefix, margins, borders and padding
+// of the style of tag take next to the lines of its content.
+func FrameWidth(tag string) int {
+	if val, has := ruppiConfig[tag]; has {
+		return val.Style.GetHorizontalFrameSize() + lipgloss.Width(val.Prefix)
+	}
+	return 0
+}
+
+// HasStyle reports whether the config has a section styling tag.
+func HasStyle(tag string) bool {
+	_, has := ruppiConfig[tag]
+	return has
+}
+
+// RenderStyle renders content in the style of tag, without its prefix and
+// infix.
+func RenderStyle(tag string, content string) string {
+	if val, has := ruppiConfig[tag]; has {
+		return val.Style.Render(content)
+	}
+	return content
+}
+
 func getDefaultTheme() Theme {
 	return Theme{
 		// Colors
@@ -326,11 +515,24 @@ func getDefaultTheme() Theme {
 		InspectorToggleKey: "?",
 		QuitKey:            "q",
 		SearchKey:          "i",
+		ImageViewerKey:     "v",
+		BackKey:            "alt+left",
+		ForwardKey:         "alt+right",
+		BookmarkKey:        "ctrl+d",
 		NewTabTooltip:      "New Tab",
 
 		// Browser
 		BrowserBackground:   "#0e0e0e",
 		InspectorBackground: "#1e1e1e",
+
+		// Syntax highlighting
+		SyntaxKeyword:  "#c678dd",
+		SyntaxString:   "#98c379",
+		SyntaxNumber:   "#d19a66",
+		SyntaxComment:  "#5c6370",
+		SyntaxFunction: "#61afef",
+		SyntaxType:     "#e5c07b",
+		SyntaxOperator: "#56b6c2",
 	}
 }
 
@@ -338,6 +540,54 @@ func GetMaxGaps() int {
 	return maxGaps
 }
 
+// GetSessionConfig returns the session configuration
+func GetSessionConfig() SessionConfig {
+	return sessionConfig
+}
+
+// GetListConfig returns the list layout configuration
+func GetListConfig() ListConfig {
+	return listConfig
+}
+
+// GetDataDir returns the directory Ruppi keeps its own files (sessions,
+// bookmarks, ...) in: the configured data-dir or the user config directory.
+func GetDataDir() string {
+	if dataDir != "" {
+		return expandHome(dataDir)
+	}
+
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "ruppi")
+	}
+	return ".ruppi"
+}
+
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
+
+// GetDownloadDir returns the directory saved files go to: the configured
+// download-dir, ~/Downloads if it exists, or the working directory.
+func GetDownloadDir() string {
+	if downloadDir != "" {
+		return expandHome(downloadDir)
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		dir := filepath.Join(home, "Downloads")
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return "."
+}
+
 func GetTheme() Theme {
 	return currentTheme
 }
@@ -380,6 +630,7 @@ func LoadConfig(path string) error {
 		return fmt.Errorf("could not open config file: %w", err)
 	}
 	defer file.Close()
+	configPath = path
 
 	scanner := bufio.NewScanner(file)
 	var currentTag string
@@ -393,18 +644,27 @@ func LoadConfig(path string) error {
 		}
 
 		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-			if currentTag != "" && currentTag != "ruppi" {
+			if isStyleSection(currentTag) {
 				ruppiConfig[currentTag] = currentInfo
 			}
 
 			currentTag = strings.Trim(line, "[]")
 			currentInfo = StyleInfo{Style: lipgloss.NewStyle()}
 		} else if currentTag != "" {
-			if currentTag == "ruppi" {
+			switch currentTag {
+			case "ruppi":
 				if err := parseRuppiSetting(line); err != nil {
 					fmt.Printf("Warning: skipping ruppi setting: %v\n", err)
 				}
-			} else {
+			case "search":
+				if err := parseSearchSetting(line); err != nil {
+					fmt.Printf("Warning: skipping search engine: %v\n", err)
+				}
+			case "keys":
+				if err := parseKeySetting(line); err != nil {
+					fmt.Printf("Warning: skipping key binding: %v\n", err)
+				}
+			default:
 				var err error
 				currentInfo, err = parseKeyValue(line, currentInfo)
 				if err != nil {
@@ -414,7 +674,7 @@ func LoadConfig(path string) error {
 		}
 	}
 
-	if currentTag != "" && currentTag != "ruppi" {
+	if isStyleSection(currentTag) {
 		ruppiConfig[currentTag] = currentInfo
 	}
 
@@ -424,3 +684,9 @@ func LoadConfig(path string) error {
 
 	return nil
 }
+
+// isStyleSection reports whether a config section styles an HTML tag rather
+// than holding settings.
+func isStyleSection(tag string) bool {
+	return tag != "" && tag != "ruppi" && tag != "search" && tag != "keys"
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	// keyBindings holds the [keys] section: the keys bound to each action,
+	// in the order they were written. Actions not listed keep the keys of
+	// the preset.
+	keyBindings = map[string][]string{}
+
+	// keyPreset picks the default keys: "default" or "vim". It is set by
+	// keys-preset in [ruppi].
+	keyPreset = "default"
+)
+
+// parseKeySetting reads a line of the [keys] section:
+//
+//	<action> <key> [<key>...]
+//
+// A key is a chord such as "ctrl+t" or a sequence of keys such as "gg" or
+// "g T" (quote sequences containing spaces). "none" unbinds the action.
+func parseKeySetting(line string) error {
+	fields, err := splitQuoted(line)
+	if err != nil {
+		return err
+	}
+	if len(fields) < 2 {
+		return fmt.Errorf("invalid key binding: %s", line)
+	}
+
+	action := strings.ToLower(fields[0])
+	keys := fields[1:]
+	if len(keys) == 1 && strings.ToLower(keys[0]) == "none" {
+		keys = nil
+	}
+	keyBindings[action] = keys
+	return nil
+}
+
+// splitQuoted splits line on whitespace, keeping quoted strings together.
+func splitQuoted(line string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	var quote rune
+	inField := false
+
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			field.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inField = true
+		case r == ' ' || r == '\t':
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote: %s", line)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+func setKeyPreset(preset string) error {
+	switch preset = strings.ToLower(preset); preset {
+	case "default", "vim":
+		keyPreset = preset
+		return nil
+	}
+	return fmt.Errorf("unknown key preset: %s", preset)
+}
+
+// GetKeyPreset returns the preset the default keys come from.
+func GetKeyPreset() string {
+	return keyPreset
+}
+
+// GetKeyBindings returns the keys configured for action in [keys], and
+// whether it was configured at all.
+func GetKeyBindings(action string) ([]string, bool) {
+	keys, ok := keyBindings[action]
+	return keys, ok
+}
+
+// ConfiguredKeyActions returns the action names set in [keys].
+func ConfiguredKeyActions() []string {
+	actions := make([]string, 0, len(keyBindings))
+	for action := range keyBindings {
+		actions = append(actions, action)
+	}
+	return actions
+}
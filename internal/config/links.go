@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Link display modes, set by link-display in [ruppi].
+const (
+	// LINK_DISPLAY_INLINE shows the URL of a link right after its text.
+	LINK_DISPLAY_INLINE = "inline"
+	// LINK_DISPLAY_FOOTNOTE numbers links, as in "text[12]", and lists
+	// their URLs at the end of the page.
+	LINK_DISPLAY_FOOTNOTE = "footnote"
+)
+
+var linkDisplay = LINK_DISPLAY_INLINE
+
+func setLinkDisplay(value string) error {
+	switch value = strings.ToLower(value); value {
+	case LINK_DISPLAY_INLINE, LINK_DISPLAY_FOOTNOTE:
+		linkDisplay = value
+		return nil
+	}
+	return fmt.Errorf("invalid link-display value: %s", value)
+}
+
+// GetLinkDisplay returns how links are shown in pages.
+func GetLinkDisplay() string {
+	return linkDisplay
+}
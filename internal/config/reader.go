@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// readerDomains are the domains whose pages open in reader mode, set by
+// reader-mode-domains in [ruppi]. "example.com" also covers its subdomains,
+// "*.example.com" only them.
+var readerDomains []string
+
+func setReaderDomains(value string) error {
+	var domains []string
+	for _, domain := range strings.Fields(strings.ReplaceAll(value, ",", " ")) {
+		domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+		if domain == "none" {
+			continue
+		}
+		if strings.Contains(domain, "/") || strings.Count(domain, "*") > 1 ||
+			strings.Contains(domain, "*") && !strings.HasPrefix(domain, "*.") {
+			return fmt.Errorf("invalid reader-mode-domains entry: %s", domain)
+		}
+		domains = append(domains, domain)
+	}
+	readerDomains = domains
+	return nil
+}
+
+// GetReaderDomains returns the domains whose pages open in reader mode.
+func GetReaderDomains() []string {
+	return readerDomains
+}
+
+// UseReaderMode reports whether the page at address opens in reader mode.
+func UseReaderMode(address string) bool {
+	u, err := url.Parse(address)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range readerDomains {
+		if sub, ok := strings.CutPrefix(domain, "*."); ok {
+			if strings.HasSuffix(host, "."+sub) {
+				return true
+			}
+		} else if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SearchEngine is a search engine picked by typing its keyword before a
+// query in the URL bar, as in "w go modules". URL is a template in which %s
+// is replaced by the escaped query.
+type SearchEngine struct {
+	Keyword string
+	URL     string
+}
+
+var (
+	searchEngines = []SearchEngine{
+		{Keyword: "ddg", URL: "https://html.duckduckgo.com/html/?q=%s"},
+		{Keyword: "g", URL: "https://www.google.com/search?q=%s"},
+		{Keyword: "w", URL: "https://en.wikipedia.org/w/index.php?search=%s"},
+		{Keyword: "gh", URL: "https://github.com/search?q=%s"},
+		{Keyword: "pkg", URL: "https://pkg.go.dev/search?q=%s"},
+	}
+	defaultSearchEngine = "ddg"
+)
+
+// parseSearchSetting reads a line of the [search] section: either
+// "default <keyword>" or "<keyword> <url template>".
+func parseSearchSetting(line string) error {
+	parts := strings.Fields(line)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid search engine: %s", line)
+	}
+
+	key := strings.ToLower(parts[0])
+	value := strings.Trim(parts[1], `"'`)
+
+	if key == "default" {
+		defaultSearchEngine = strings.ToLower(value)
+		return nil
+	}
+
+	if !strings.Contains(value, "%s") {
+		return fmt.Errorf("search engine %s has no %%s in its URL: %s", key, value)
+	}
+
+	for i, engine := range searchEngines {
+		if engine.Keyword == key {
+			searchEngines[i].URL = value
+			return nil
+		}
+	}
+	searchEngines = append(searchEngines, SearchEngine{Keyword: key, URL: value})
+	return nil
+}
+
+// GetSearchEngines returns every configured search engine.
+func GetSearchEngines() []SearchEngine {
+	return searchEngines
+}
+
+// GetSearchEngine returns the search engine with keyword.
+func GetSearchEngine(keyword string) (SearchEngine, bool) {
+	keyword = strings.ToLower(keyword)
+	for _, engine := range searchEngines {
+		if engine.Keyword == keyword {
+			return engine, true
+		}
+	}
+	return SearchEngine{}, false
+}
+
+// GetDefaultSearchEngine returns the engine used for queries without a
+// keyword, falling back to the first engine if the default doesn't exist.
+func GetDefaultSearchEngine() SearchEngine {
+	if engine, ok := GetSearchEngine(defaultSearchEngine); ok {
+		return engine
+	}
+	return searchEngines[0]
+}
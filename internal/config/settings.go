@@ -0,0 +1,97 @@
+package config
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Setting is a [ruppi] setting and the value in effect.
+type Setting struct {
+	Key   string
+	Value string
+}
+
+// configPath is the file LoadConfig last read.
+var configPath string
+
+// GetConfigPath returns the config file that was loaded, or "" if none was.
+func GetConfigPath() string {
+	return configPath
+}
+
+// Settings returns every [ruppi] setting with its current value, in the order
+// of the example config file.
+func Settings() []Setting {
+	t := currentTheme
+	s := sixelConfig
+	return []Setting{
+		{"max-gaps", strconv.Itoa(maxGaps)},
+
+		{"tab-color", t.TabColor},
+		{"tab-active-color", t.TabActiveColor},
+		{"tab-text-color", t.TabTextColor},
+		{"tab-active-text-color", t.TabActiveTextColor},
+		{"background-color", t.BackgroundColor},
+		{"status-bar-color", t.StatusBarColor},
+		{"browser-background", t.BrowserBackground},
+		{"inspector-background", t.InspectorBackground},
+		{"browser-foreground", t.BrowserForeground},
+		{"inspector-foreground", t.InspectorForeground},
+
+		{"syntax-keyword", t.SyntaxKeyword},
+		{"syntax-string", t.SyntaxString},
+		{"syntax-number", t.SyntaxNumber},
+		{"syntax-comment", t.SyntaxComment},
+		{"syntax-function", t.SyntaxFunction},
+		{"syntax-type", t.SyntaxType},
+		{"syntax-operator", t.SyntaxOperator},
+
+		{"tab-close-icon", t.TabCloseIcon},
+		{"tab-new-icon", t.TabNewIcon},
+		{"tab-prev-icon", t.TabPrevIcon},
+		{"tab-next-icon", t.TabNextIcon},
+		{"search-icon", t.SearchIcon},
+
+		{"search-placeholder", t.SearchPlaceholder},
+		{"inspector-toggle-key", t.InspectorToggleKey},
+		{"quit-key", t.QuitKey},
+		{"search-key", t.SearchKey},
+		{"image-viewer-key", t.ImageViewerKey},
+		{"back-key", t.BackKey},
+		{"forward-key", t.ForwardKey},
+		{"bookmark-key", t.BookmarkKey},
+		{"new-tab-tooltip", t.NewTabTooltip},
+		{"keys-preset", keyPreset},
+
+		{"enable-sixel", strconv.FormatBool(s.Enabled)},
+		{"sixel-max-width", strconv.Itoa(s.MaxWidth)},
+		{"sixel-max-height", strconv.Itoa(s.MaxHeight)},
+		{"sixel-colors", strconv.Itoa(s.Colors)},
+		{"sixel-dither", s.Dither},
+		{"sixel-workers", strconv.Itoa(s.Workers)},
+		{"animate-images", strconv.FormatBool(s.Animate)},
+		{"cell-width", strconv.Itoa(s.CellWidth)},
+		{"cell-height", strconv.Itoa(s.CellHeight)},
+
+		{"download-dir", GetDownloadDir()},
+		{"data-dir", GetDataDir()},
+		{"restore-session", strconv.FormatBool(sessionConfig.Restore)},
+		{"session-save-interval", strconv.Itoa(sessionConfig.SaveInterval)},
+
+		{"list-indent", strconv.Itoa(listConfig.Indent)},
+		{"list-bullets", strings.Join(listConfig.Bullets, " ")},
+		{"link-display", linkDisplay},
+		{"reader-mode-domains", strings.Join(readerDomains, " ")},
+	}
+}
+
+// StyledTags returns the HTML tags that have a style section, sorted.
+func StyledTags() []string {
+	tags := make([]string, 0, len(ruppiConfig))
+	for tag := range ruppiConfig {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
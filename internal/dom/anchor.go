@@ -0,0 +1,111 @@
+package dom
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// anchorMarker is written where a heading or an element with an id (or an
+// <a name>) starts. It looks like an escape sequence, so word wrapping sees
+// no width, and LocateAnchors takes it out once the final layout is known.
+const anchorMarker = "\x1b[%d;7777z"
+
+var anchorMarkerPattern = regexp.MustCompile(`\x1b\[(\d+);7777z`)
+
+// anchorName is the name a fragment can point at n by: its id, or the name
+// of an <a>.
+func (n *Node) anchorName() string {
+	if id := strings.TrimSpace(n.Element.Attrs["id"]); id != "" {
+		return id
+	}
+	if n.Element.NodeType == A {
+		return strings.TrimSpace(n.Element.Attrs["name"])
+	}
+	return ""
+}
+
+// Heading is an entry of the page outline. Anchor is the index of its
+// marker in Page.Anchors.
+type Heading struct {
+	Level  int
+	Text   string
+	Anchor int
+}
+
+// anchor records name, which is empty for a heading without an id, as an
+// anchor of the page and returns the marker to write where it starts and its
+// index.
+func (s *renderState) anchor(name string) (string, int) {
+	s.page.Anchors = append(s.page.Anchors, name)
+	index := len(s.page.Anchors) - 1
+	return fmt.Sprintf(anchorMarker, index), index
+}
+
+// markAnchor puts the marker in front of output when n can be linked to or
+// is a heading, and adds headings to the outline.
+func (n *Node) markAnchor(state *renderState, output string) string {
+	name := n.anchorName()
+	isHeading := n.Element.NodeType >= H1 && n.Element.NodeType <= H6
+	if name == "" && !isHeading {
+		return output
+	}
+
+	marker, index := state.anchor(name)
+	if isHeading {
+		text := strings.Join(strings.Fields(n.textContent()), " ")
+		if text != "" {
+			state.page.Headings = append(state.page.Headings, Heading{
+				Level:  int(n.Element.NodeType-H1) + 1,
+				Text:   text,
+				Anchor: index,
+			})
+		}
+	}
+	return marker + output
+}
+
+// textContent is the text of n and everything below it.
+func (n *Node) textContent() string {
+	if len(n.Children) == 0 {
+		return n.InnerText
+	}
+
+	var sb strings.Builder
+	for _, child := range n.Children {
+		if t := child.Element.NodeType; t == STYLE || t == SCRIPT {
+			continue
+		}
+		sb.WriteString(child.textContent())
+		sb.WriteString(" ")
+	}
+	return sb.String()
+}
+
+// LocateAnchors removes the anchor markers from the wrapped page and returns
+// it with the line every marker ended up on, by index, or -1 for markers lost
+// along the way.
+func LocateAnchors(text string, count int) (string, []int) {
+	lines := make([]int, count)
+	for i := range lines {
+		lines[i] = -1
+	}
+	if count == 0 {
+		return text, lines
+	}
+
+	rows := strings.Split(text, "\n")
+	for i, row := range rows {
+		if !strings.Contains(row, ";7777z") {
+			continue
+		}
+		for _, m := range anchorMarkerPattern.FindAllStringSubmatch(row, -1) {
+			if index, err := strconv.Atoi(m[1]); err == nil && index < count {
+				lines[index] = i
+			}
+		}
+		rows[i] = anchorMarkerPattern.ReplaceAllString(row, "")
+	}
+	return strings.Join(rows, "\n"), lines
+}
@@ -0,0 +1,373 @@
+package dom
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// Terminals show text left to right, so right-to-left scripts such as
+// Hebrew and Arabic are put in the order they are read in before they are
+// shown: each line is reordered with the Unicode bidirectional algorithm,
+// and the lines of right-to-left paragraphs are aligned to the right.
+
+// direction is the direction text is written in: dirAuto takes it from the
+// first letter with a direction of its own.
+type direction int
+
+const (
+	dirAuto direction = iota
+	dirLTR
+	dirRTL
+)
+
+// lrm is the left-to-right mark, put before a line to make it left to right.
+const lrm = '\u200e'
+
+// parseDirection reads the dir attribute of an element, returning false
+// when it doesn't set one.
+func parseDirection(dir string) (direction, bool) {
+	switch strings.ToLower(strings.TrimSpace(dir)) {
+	case "ltr":
+		return dirLTR, true
+	case "rtl":
+		return dirRTL, true
+	case "auto":
+		return dirAuto, true
+	}
+	return dirAuto, false
+}
+
+// isRTL reports whether text written in dir goes right to left.
+func (dir direction) isRTL(text string) bool {
+	switch dir {
+	case dirLTR:
+		return false
+	case dirRTL:
+		return true
+	}
+
+	for _, r := range stripANSICodes(text) {
+		switch props, _ := bidi.LookupRune(r); props.Class() {
+		case bidi.L:
+			return false
+		case bidi.R, bidi.AL:
+			return true
+		}
+	}
+	return false
+}
+
+// orderLines puts the lines of a paragraph written in dir in the order
+// they are shown in. The lines of a right-to-left paragraph are aligned to
+// the right of width columns.
+func orderLines(text string, dir direction, width int) string {
+	rtl := dir.isRTL(text)
+	if !rtl && !hasRTL(text) {
+		return text
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if hasImageMarker(line) {
+			continue
+		}
+		lines[i] = visualOrder(line, rtl)
+		if rtl && width > 0 && isVisible(line) {
+			lines[i] = strings.Repeat(" ", max(width-lipgloss.Width(lines[i]), 0)) + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// VisualOrder puts text such as a page title in the order it is shown in,
+// going by its first letter with a direction.
+func VisualOrder(text string) string {
+	if !hasRTL(text) {
+		return text
+	}
+	return visualOrder(text, dirAuto.isRTL(text))
+}
+
+// hasRTL reports whether text has letters or digits that go right to left.
+func hasRTL(text string) bool {
+	for _, r := range text {
+		if r < '\u0590' {
+			continue
+		}
+		switch props, _ := bidi.LookupRune(r); props.Class() {
+		case bidi.R, bidi.AL, bidi.AN:
+			return true
+		}
+	}
+	return false
+}
+
+// cell is a character of a line with the styling it is shown in.
+type cell struct {
+	r     rune
+	style string
+}
+
+// visualOrder reorders a line written in logical order, rtl when it goes
+// right to left. Characters keep their styling; the escape sequences that
+// aren't styling, such as anchor markers, are put at the start.
+func visualOrder(line string, rtl bool) string {
+	markers, cells := splitCells(line)
+	if len(cells) == 0 {
+		return line
+	}
+
+	runes := make([]rune, len(cells)+1)
+	runes[0] = lrm
+	for i, c := range cells {
+		runes[i+1] = c.r
+	}
+
+	var p bidi.Paragraph
+	var opts []bidi.Option
+	text := string(runes)
+	if rtl {
+		text = string(runes[1:])
+		opts = append(opts, bidi.DefaultDirection(bidi.RightToLeft))
+	}
+	if _, err := p.SetString(text, opts...); err != nil {
+		return line
+	}
+	ordering, err := p.Order()
+	if err != nil {
+		return line
+	}
+
+	offset := 0
+	if !rtl {
+		// leave the mark out again
+		offset = 1
+	}
+	runs := pairBrackets(cells, levelRuns(ordering, rtl, offset))
+	reorderRuns(runs)
+
+	var sb strings.Builder
+	sb.WriteString(markers)
+	style := ""
+	for _, run := range runs {
+		for i := range run.end - run.start {
+			index := run.start + i
+			if run.level%2 == 1 {
+				index = run.end - 1 - i
+			}
+			c := cells[index]
+			if c.style != style {
+				if style != "" {
+					sb.WriteString(resetStyle)
+				}
+				sb.WriteString(c.style)
+				style = c.style
+			}
+			if run.level%2 == 1 {
+				c.r = mirror(c.r)
+			}
+			sb.WriteRune(c.r)
+		}
+	}
+	if style != "" {
+		sb.WriteString(resetStyle)
+	}
+	return sb.String()
+}
+
+// splitCells splits line into its characters, each with the styling in
+// effect where it is, and the other escape sequences it has.
+func splitCells(line string) (string, []cell) {
+	var (
+		markers  strings.Builder
+		escape   strings.Builder
+		cells    []cell
+		style    string
+		inEscape bool
+	)
+	for _, r := range line {
+		switch {
+		case r == '\x1b':
+			inEscape = true
+			escape.Reset()
+			escape.WriteRune(r)
+		case inEscape:
+			escape.WriteRune(r)
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+				inEscape = false
+				switch seq := escape.String(); {
+				case seq == resetStyle || seq == "\x1b[m":
+					style = ""
+				case r == 'm':
+					style += seq
+				default:
+					markers.WriteString(seq)
+				}
+			}
+		default:
+			cells = append(cells, cell{r: r, style: style})
+		}
+	}
+	return markers.String(), cells
+}
+
+// levelRun is a run of cells, from start up to end, at an embedding level
+// of the bidirectional algorithm: odd levels go right to left.
+type levelRun struct {
+	start, end int
+	level      int
+	// letters is set when the run has letters that go left to right.
+	letters bool
+}
+
+// levelRuns returns the runs of ordering as runs of cells, offset being the
+// number of runes put before the cells. The ordering only tells left to
+// right from right to left, so levels are told apart the way the algorithm
+// sets them: left to right text inside a right-to-left paragraph is a level
+// above it, and so are numbers between right-to-left words.
+func levelRuns(ordering bidi.Ordering, rtl bool, offset int) []levelRun {
+	var runs []levelRun
+	for i := range ordering.NumRuns() {
+		run := ordering.Run(i)
+		start, end := run.Pos()
+		start, end = max(start-offset, 0), end+1-offset
+		if end <= start {
+			continue
+		}
+
+		level := 0
+		switch {
+		case run.Direction() == bidi.RightToLeft:
+			level = 1
+		case rtl:
+			level = 2
+		}
+		runs = append(runs, levelRun{start: start, end: end, level: level, letters: hasStrongLTR(run.String())})
+	}
+
+	if !rtl {
+		for i := 1; i+1 < len(runs); i++ {
+			if runs[i].level == 0 && runs[i-1].level == 1 && runs[i+1].level == 1 && !runs[i].letters {
+				runs[i].level = 2
+			}
+		}
+	}
+	return runs
+}
+
+// pairBrackets gives a closing bracket the level of the bracket it closes
+// when the text between them is at that level too, as the algorithm pairs
+// brackets. The ordering leaves a bracket at the end of a line at the level
+// of the paragraph instead.
+func pairBrackets(cells []cell, runs []levelRun) []levelRun {
+	levels := make([]int, len(cells))
+	for _, run := range runs {
+		for i := run.start; i < run.end; i++ {
+			levels[i] = run.level
+		}
+	}
+
+	changed := false
+	var open []int
+	for i, c := range cells {
+		props, _ := bidi.LookupRune(c.r)
+		if props.IsOpeningBracket() {
+			open = append(open, i)
+			continue
+		}
+		if !props.IsBracket() {
+			continue
+		}
+		for j := len(open) - 1; j >= 0; j-- {
+			o := open[j]
+			if mirror(cells[o].r) != c.r {
+				continue
+			}
+			open = open[:j]
+			if levels[i] != levels[o] && innerLevel(cells, levels, o+1, i) == levels[o] {
+				levels[i] = levels[o]
+				changed = true
+			}
+			break
+		}
+	}
+	if !changed {
+		return runs
+	}
+
+	var paired []levelRun
+	for i, level := range levels {
+		if len(paired) > 0 && paired[len(paired)-1].level == level {
+			paired[len(paired)-1].end = i + 1
+		} else {
+			paired = append(paired, levelRun{start: i, end: i + 1, level: level})
+		}
+	}
+	return paired
+}
+
+// innerLevel returns the level of the first letter of the cells from start
+// up to end, or -1 when there is none.
+func innerLevel(cells []cell, levels []int, start, end int) int {
+	for i := start; i < end; i++ {
+		switch props, _ := bidi.LookupRune(cells[i].r); props.Class() {
+		case bidi.L, bidi.R, bidi.AL:
+			return levels[i]
+		}
+	}
+	return -1
+}
+
+// hasStrongLTR reports whether text has letters that go left to right.
+func hasStrongLTR(text string) bool {
+	for _, r := range text {
+		if props, _ := bidi.LookupRune(r); props.Class() == bidi.L {
+			return true
+		}
+	}
+	return false
+}
+
+// reorderRuns puts runs in the order they are shown in: from the highest
+// level down to the lowest odd one, every sequence of runs at that level or
+// above is reversed.
+func reorderRuns(runs []levelRun) {
+	highest, lowestOdd := 0, -1
+	for _, run := range runs {
+		highest = max(highest, run.level)
+		if run.level%2 == 1 && (lowestOdd < 0 || run.level < lowestOdd) {
+			lowestOdd = run.level
+		}
+	}
+	if lowestOdd < 0 {
+		lowestOdd = highest + 1
+	}
+
+	for level := highest; level >= lowestOdd; level-- {
+		for i := 0; i < len(runs); {
+			if runs[i].level < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(runs) && runs[j].level >= level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				runs[a], runs[b] = runs[b], runs[a]
+			}
+			i = j
+		}
+	}
+}
+
+// mirror returns the bracket facing the other way for a bracket shown in
+// right-to-left text, and other characters as they are.
+func mirror(r rune) rune {
+	if props, _ := bidi.LookupRune(r); !props.IsBracket() {
+		return r
+	}
+	return []rune(bidi.ReverseString(string(r)))[0]
+}
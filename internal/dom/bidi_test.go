@@ -0,0 +1,87 @@
+package dom
+
+import "testing"
+
+func TestVisualOrder(t *testing.T) {
+	const bold = "\x1b[1m"
+	tests := []struct {
+		name string
+		line string
+		rtl  bool
+		want string
+	}{
+		{"right to left", "שלום עולם", true, "םלוע םולש"},
+		{"arabic", "مرحبا بالعالم", true, "ملاعلاب ابحرم"},
+		{"left to right", "hello world", false, "hello world"},
+		{"right to left inside left to right", "say שלום now", false, "say םולש now"},
+		{"left to right inside right to left", "שלום hello world עולם", true, "םלוע hello world םולש"},
+		{"number inside right to left", "מחיר 120 שקל", true, "לקש 120 ריחמ"},
+		{"number and percent", "עברית 1.5%", true, "1.5% תירבע"},
+		{"number between right to left words", "a אב 12 גד b", false, "a דג 12 בא b"},
+		{"brackets in right to left", "(שלום)", true, "(םולש)"},
+		{"brackets at end of line", "שלום (עולם)", false, "(םלוע) םולש"},
+		{"brackets around left to right", "שלום (Go) עולם", true, "םלוע (Go) םולש"},
+		{"styling kept", bold + "שלום" + resetStyle + " עולם", true, "םלוע " + bold + "םולש" + resetStyle},
+		{"markers first", "שלום\x1b[3;7777z עולם", true, "\x1b[3;7777zםלוע םולש"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := visualOrder(tt.line, tt.rtl); got != tt.want {
+				t.Errorf("visualOrder(%q, %v) = %q, want %q", tt.line, tt.rtl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		dir   direction
+		width int
+		want  string
+	}{
+		{"left to right untouched", "hello\nworld", dirAuto, 12, "hello\nworld"},
+		{"auto right to left aligned right", "שלום עולם", dirAuto, 12, "   םלוע םולש"},
+		{"auto left to right with right to left word", "hello שלום", dirAuto, 12, "hello םולש"},
+		{"dir=rtl aligns every line", "שלום\nabc", dirRTL, 6, "  םולש\n   abc"},
+		{"dir=ltr keeps left", "שלום עולם", dirLTR, 12, "םלוע םולש"},
+		{"no width", "שלום", dirRTL, 0, "םולש"},
+		{"empty lines stay empty", "שלום\n\nעולם", dirRTL, 6, "  םולש\n\n  םלוע"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orderLines(tt.text, tt.dir, tt.width); got != tt.want {
+				t.Errorf("orderLines(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReorderRuns(t *testing.T) {
+	// levels 0 1 1 2 1 0: the left to right run inside the right to left
+	// ones keeps its order, the right to left ones are reversed
+	runs := []levelRun{{start: 0, level: 0}, {start: 1, level: 1}, {start: 2, level: 1}, {start: 3, level: 2}, {start: 4, level: 1}, {start: 5, level: 0}}
+	reorderRuns(runs)
+
+	var got []int
+	for _, run := range runs {
+		got = append(got, run.start)
+	}
+	want := []int{0, 4, 3, 2, 1, 5}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("order %v, want %v", got, want)
+		}
+	}
+}
+
+func TestMirror(t *testing.T) {
+	for r, want := range map[rune]rune{'(': ')', ')': '(', '[': ']', '{': '}', 'a': 'a', '1': '1'} {
+		if got := mirror(r); got != want {
+			t.Errorf("mirror(%q) = %q, want %q", r, got, want)
+		}
+	}
+}
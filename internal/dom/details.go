@@ -0,0 +1,129 @@
+package dom
+
+import (
+	"ruppi/internal/config"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Details is a <details> element of a page, which can be opened and closed.
+// Anchor is the index of the marker on its summary line in Page.Anchors.
+type Details struct {
+	Anchor int
+	attrs  map[string]string
+}
+
+// Open reports whether the content of the details is shown.
+func (d Details) Open() bool {
+	_, open := d.attrs["open"]
+	return open
+}
+
+// Toggle opens or closes the details in the document they were rendered
+// from. The page shows it once rendered again.
+func (d Details) Toggle() {
+	if d.attrs == nil {
+		return
+	}
+	if d.Open() {
+		delete(d.attrs, "open")
+	} else {
+		d.attrs["open"] = ""
+	}
+}
+
+// closedMarker and openMarker start the summary line of a <details>.
+const (
+	closedMarker = "▸ "
+	openMarker   = "▾ "
+)
+
+// LabelDetails draws label in place of the marker on the summary line of a
+// <details>, to pick it with the keyboard.
+func LabelDetails(line, label string) string {
+	i := strings.Index(line, closedMarker)
+	if open := strings.Index(line, openMarker); i < 0 || open >= 0 && open < i {
+		i = open
+	}
+	if i < 0 {
+		return line
+	}
+	return line[:i] + label + " " + line[i+len(closedMarker):]
+}
+
+// layoutDetails lays out the <details> n: its summary behind a marker of
+// whether it is open and, when it is, the rest of it indented below.
+func (n *Node) layoutDetails(state *renderState, url string, isKitty bool) box {
+	details := Details{attrs: n.Element.Attrs}
+	marker, index := state.anchor("")
+	details.Anchor = index
+	state.page.Details = append(state.page.Details, details)
+
+	summary := SummaryStyle.Render("Details")
+	body := Node{Element: ElementData{NodeType: DIV, Attrs: map[string]string{}}}
+	hasSummary := false
+	for _, child := range n.Children {
+		if child.Element.NodeType == SUMMARY && !hasSummary {
+			summary = strings.TrimSpace(child.layoutBlock(state, url, isKitty).text)
+			hasSummary = true
+			continue
+		}
+		body.Children = append(body.Children, child)
+	}
+
+	toggle := closedMarker
+	if details.Open() {
+		toggle = openMarker
+	}
+	output := marker + toggle + summary
+	if details.Open() && len(body.Children) > 0 {
+		indent := config.GetListConfig().Indent
+		bodyState := *state
+		bodyState.indent(indent)
+		if content := body.layoutChildren(&bodyState, url, isKitty); isVisible(content.text) {
+			output = hangingIndent(output+"\n"+strings.Trim(content.text, "\n"), indent)
+		}
+	}
+	return box{text: n.markAnchor(state, output)}
+}
+
+// styled renders content in the style of tag from the config, or in style
+// when the config has no section for it.
+func styled(tag string, style lipgloss.Style, content string) string {
+	if config.HasStyle(tag) {
+		return config.AddStyle(tag, content)
+	}
+	if strings.TrimSpace(content) == "" {
+		return content
+	}
+	return style.Render(content)
+}
+
+var (
+	subscripts   = map[rune]rune{'0': '₀', '1': '₁', '2': '₂', '3': '₃', '4': '₄', '5': '₅', '6': '₆', '7': '₇', '8': '₈', '9': '₉', '+': '₊', '-': '₋', '=': '₌', '(': '₍', ')': '₎'}
+	superscripts = map[rune]rune{'0': '⁰', '1': '¹', '2': '²', '3': '³', '4': '⁴', '5': '⁵', '6': '⁶', '7': '⁷', '8': '⁸', '9': '⁹', '+': '⁺', '-': '⁻', '=': '⁼', '(': '⁽', ')': '⁾', 'n': 'ⁿ', 'i': 'ⁱ'}
+)
+
+// scriptText writes the text of a <sub> or <sup> in the Unicode sub or
+// superscript characters of scripts, or after mark, as in x^2, when some
+// of it has none.
+func scriptText(content string, scripts map[rune]rune, mark string) string {
+	text := strings.TrimSpace(content)
+	if text == "" || strings.ContainsRune(text, '\x1b') {
+		return content
+	}
+
+	var sb strings.Builder
+	for _, r := range text {
+		script, ok := scripts[r]
+		if !ok {
+			if strings.ContainsRune(text, ' ') {
+				return mark + "(" + text + ")"
+			}
+			return mark + text
+		}
+		sb.WriteRune(script)
+	}
+	return sb.String()
+}
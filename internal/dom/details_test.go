@@ -0,0 +1,118 @@
+package dom
+
+import "testing"
+
+func details(open bool, children ...Node) Node {
+	n := element("details", children...)
+	if open {
+		n.Element.Attrs["open"] = ""
+	}
+	return n
+}
+
+func TestLayoutDetails(t *testing.T) {
+	tests := []struct {
+		name string
+		node Node
+		want string
+	}{
+		{
+			name: "closed",
+			node: details(false, element("summary", text("More")), element("p", text("Hidden"))),
+			want: "▸ More",
+		},
+		{
+			name: "open",
+			node: details(true, element("summary", text("More")), element("p", text("Shown"))),
+			want: "▾ More\n  Shown",
+		},
+		{
+			name: "no summary",
+			node: details(false, element("p", text("Hidden"))),
+			want: "▸ Details",
+		},
+		{
+			name: "open and empty",
+			node: details(true, element("summary", text("More"))),
+			want: "▾ More",
+		},
+		{
+			name: "only the first summary",
+			node: details(true, element("summary", text("One")), element("summary", text("Two"))),
+			want: "▾ One\n  Two",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := layout(tt.node, 40); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetailsToggle(t *testing.T) {
+	n := details(false, element("summary", text("More")), element("p", text("Body")))
+	state := &renderState{width: 40, lineWidth: 40, page: &Page{}}
+	n.layoutBlock(state, "", false)
+	if len(state.page.Details) != 1 {
+		t.Fatalf("page has %d details, want 1", len(state.page.Details))
+	}
+
+	d := state.page.Details[0]
+	d.Toggle()
+	if !d.Open() {
+		t.Fatal("details still closed after Toggle")
+	}
+	if got, want := layout(n, 40), "▾ More\n  Body"; got != want {
+		t.Errorf("after Toggle got %q, want %q", got, want)
+	}
+	d.Toggle()
+	if got, want := layout(n, 40), "▸ More"; got != want {
+		t.Errorf("after second Toggle got %q, want %q", got, want)
+	}
+}
+
+func TestLabelDetails(t *testing.T) {
+	tests := []struct {
+		line, want string
+	}{
+		{"▸ More", "1 More"},
+		{"  ▾ More", "  1 More"},
+		{"▾ Open ▸ closed", "1 Open ▸ closed"},
+		{"no marker", "no marker"},
+	}
+	for _, tt := range tests {
+		if got := LabelDetails(tt.line, "1"); got != tt.want {
+			t.Errorf("LabelDetails(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestScriptText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		scripts map[rune]rune
+		mark    string
+		want    string
+	}{
+		{"superscript digits", "2", superscripts, "^", "²"},
+		{"superscript n", "n+1", superscripts, "^", "ⁿ⁺¹"},
+		{"subscript", "2", subscripts, "_", "₂"},
+		{"no script character", "th", superscripts, "^", "^th"},
+		{"subscript letters", "max", subscripts, "_", "_max"},
+		{"spaces get brackets", "a b", superscripts, "^", "^(a b)"},
+		{"space around", " 10 ", superscripts, "^", "¹⁰"},
+		{"empty", " ", superscripts, "^", " "},
+		{"styled is kept", "\x1b[1m2\x1b[0m", superscripts, "^", "\x1b[1m2\x1b[0m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scriptText(tt.content, tt.scripts, tt.mark); got != tt.want {
+				t.Errorf("scriptText(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
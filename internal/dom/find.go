@@ -0,0 +1,143 @@
+package dom
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	matchHighlight        = "\x1b[7m"
+	currentMatchHighlight = "\x1b[30;103m"
+	resetStyle            = "\x1b[0m"
+)
+
+// Match is a piece of the rendered page found by FindText. Start and End
+// count the runes of the line with its ANSI codes left out.
+type Match struct {
+	Line  int
+	Start int
+	End   int
+}
+
+// FindText returns where query appears in the rendered page, ignoring the
+// ANSI codes of its styling. As with vim's smartcase, case is ignored unless
+// query has an upper case letter.
+func FindText(rendered, query string) []Match {
+	if query == "" {
+		return nil
+	}
+
+	foldCase := strings.ToLower(query) == query
+	pattern := []rune(query)
+
+	var matches []Match
+	for i, line := range strings.Split(rendered, "\n") {
+		if isImageLine(line) {
+			continue
+		}
+
+		text := []rune(stripANSICodes(line))
+		if foldCase {
+			for j, r := range text {
+				text[j] = unicode.ToLower(r)
+			}
+		}
+
+		for start := 0; start+len(pattern) <= len(text); {
+			if string(text[start:start+len(pattern)]) == string(pattern) {
+				matches = append(matches, Match{Line: i, Start: start, End: start + len(pattern)})
+				start += len(pattern)
+			} else {
+				start++
+			}
+		}
+	}
+	return matches
+}
+
+// isImageLine reports whether line draws an image, whose escape sequences
+// aren't text.
+func isImageLine(line string) bool {
+	return strings.Contains(line, "\x1bP") || strings.Contains(line, "\x1b_G")
+}
+
+// HighlightMatches marks the matches in the rendered page, the one at index
+// current differently from the rest.
+func HighlightMatches(rendered string, matches []Match, current int) string {
+	if len(matches) == 0 {
+		return rendered
+	}
+
+	byLine := map[int][]int{}
+	for i, m := range matches {
+		byLine[m.Line] = append(byLine[m.Line], i)
+	}
+
+	lines := strings.Split(rendered, "\n")
+	for line, indices := range byLine {
+		if line < len(lines) {
+			lines[line] = highlightLine(lines[line], matches, indices, current)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// highlightLine marks the matches at indices, all on line. The line's own
+// styling is kept: the highlight is set again after each of its codes inside
+// a match, and the codes seen so far are restored after a match ends.
+func highlightLine(line string, matches []Match, indices []int, current int) string {
+	var (
+		b        strings.Builder
+		escape   strings.Builder
+		active   string
+		on       string
+		next     int
+		pos      int
+		inEscape bool
+	)
+
+	for _, r := range line {
+		if r == '\x1b' {
+			inEscape = true
+			escape.Reset()
+			escape.WriteRune(r)
+			b.WriteRune(r)
+			continue
+		}
+		if inEscape {
+			escape.WriteRune(r)
+			b.WriteRune(r)
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+				inEscape = false
+				if seq := escape.String(); seq == resetStyle || seq == "\x1b[m" {
+					active = ""
+				} else if r == 'm' {
+					active += seq
+				}
+				b.WriteString(on)
+			}
+			continue
+		}
+
+		if on != "" && pos == matches[indices[next]].End {
+			b.WriteString(resetStyle + active)
+			on = ""
+			next++
+		}
+		if on == "" && next < len(indices) && pos == matches[indices[next]].Start {
+			on = matchHighlight
+			if indices[next] == current {
+				on = currentMatchHighlight
+			}
+			b.WriteString(on)
+		}
+
+		b.WriteRune(r)
+		pos++
+	}
+
+	if on != "" {
+		b.WriteString(resetStyle + active)
+	}
+	return b.String()
+}
@@ -0,0 +1,119 @@
+package dom
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindText(t *testing.T) {
+	const bold = "\x1b[1m"
+	tests := []struct {
+		name     string
+		rendered string
+		query    string
+		want     []Match
+	}{
+		{
+			name:     "lower case query ignores case",
+			rendered: "Go go GO",
+			query:    "go",
+			want:     []Match{{0, 0, 2}, {0, 3, 5}, {0, 6, 8}},
+		},
+		{
+			name:     "upper case in query matches case",
+			rendered: "Go go GO",
+			query:    "Go",
+			want:     []Match{{0, 0, 2}},
+		},
+		{
+			name:     "match across escape code",
+			rendered: "say " + bold + "hel" + resetStyle + "lo\nhello",
+			query:    "hello",
+			want:     []Match{{0, 4, 9}, {1, 0, 5}},
+		},
+		{
+			name:     "adjacent matches",
+			rendered: "abab",
+			query:    "ab",
+			want:     []Match{{0, 0, 2}, {0, 2, 4}},
+		},
+		{
+			name:     "matches don't overlap",
+			rendered: "aaa",
+			query:    "aa",
+			want:     []Match{{0, 0, 2}},
+		},
+		{
+			name:     "image lines are skipped",
+			rendered: "\x1bPq#0hello\x1b\\\nhello",
+			query:    "hello",
+			want:     []Match{{1, 0, 5}},
+		},
+		{
+			name:     "empty query",
+			rendered: "hello",
+			query:    "",
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindText(tt.rendered, tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindText(%q, %q) = %v, want %v", tt.rendered, tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightMatches(t *testing.T) {
+	const bold = "\x1b[1m"
+	tests := []struct {
+		name     string
+		rendered string
+		query    string
+		current  int
+		want     string
+	}{
+		{
+			name:     "current match",
+			rendered: "a b a",
+			query:    "a",
+			current:  1,
+			want: matchHighlight + "a" + resetStyle + " b " +
+				currentMatchHighlight + "a" + resetStyle,
+		},
+		{
+			name:     "escape code inside match",
+			rendered: "x" + bold + "ab" + resetStyle + "cd",
+			query:    "abc",
+			current:  0,
+			want: "x" + bold + currentMatchHighlight + "ab" + resetStyle + currentMatchHighlight +
+				"c" + resetStyle + "d",
+		},
+		{
+			name:     "styling restored after match",
+			rendered: bold + "abcd" + resetStyle,
+			query:    "bc",
+			current:  -1,
+			want:     bold + "a" + matchHighlight + "bc" + resetStyle + bold + "d" + resetStyle,
+		},
+		{
+			name:     "adjacent matches",
+			rendered: "abab",
+			query:    "ab",
+			current:  1,
+			want: matchHighlight + "ab" + resetStyle +
+				currentMatchHighlight + "ab" + resetStyle,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := FindText(tt.rendered, tt.query)
+			if got := HighlightMatches(tt.rendered, matches, tt.current); got != tt.want {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
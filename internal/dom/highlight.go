@@ -0,0 +1,191 @@
+package dom
+
+import (
+	"ruppi/internal/config"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// languagePrefixes mark the language of a code block in its class, as in
+// "language-go" or "lang-go".
+var languagePrefixes = []string{"language-", "lang-", "highlight-source-", "brush:"}
+
+// highlightCode returns the code in the PRE n highlighted for the language
+// it is marked with, or false when it isn't marked with one we know.
+func (n *Node) highlightCode() (string, bool) {
+	lexer := n.codeLexer()
+	if lexer == nil {
+		return "", false
+	}
+
+	code := expandTabs(strings.TrimSuffix(n.preText(), "\n"))
+	tokens, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return "", false
+	}
+
+	styles := newSyntaxStyles()
+	var sb strings.Builder
+	for token := tokens(); token != chroma.EOF; token = tokens() {
+		style, ok := styles.of(token.Type)
+		if !ok {
+			sb.WriteString(token.Value)
+			continue
+		}
+
+		// styles are rendered line by line, so a token running over
+		// several lines isn't padded into a block
+		for i, line := range strings.Split(token.Value, "\n") {
+			if i > 0 {
+				sb.WriteRune('\n')
+			}
+			if line != "" {
+				sb.WriteString(style.Render(line))
+			}
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), true
+}
+
+// codeLexer finds the language of the PRE n, on itself or on a <code> it
+// wraps, the way common highlighters mark it: a class such as
+// "language-go", or a data-lang or lang attribute. A bare class such as "go"
+// is only taken for the language on the <code>, since on the PRE it is more
+// likely to be there for styling, as "c", "diff" or "html" could be.
+func (n *Node) codeLexer() chroma.Lexer {
+	candidates := []*Node{n}
+	if code := n.onlyChild(); code != nil && code.Element.NodeType == CODE {
+		candidates = append(candidates, code)
+	}
+
+	for _, c := range candidates {
+		attrs := c.Element.Attrs
+		for _, name := range []string{attrs["data-lang"], attrs["data-language"], attrs["lang"]} {
+			if lexer := namedLexer(name); lexer != nil {
+				return lexer
+			}
+		}
+
+		for _, class := range strings.Fields(attrs["class"]) {
+			for _, prefix := range languagePrefixes {
+				if name, ok := strings.CutPrefix(class, prefix); ok {
+					if lexer := lexers.Get(name); lexer != nil && !isPlainText(lexer) {
+						return lexer
+					}
+				}
+			}
+			if c.Element.NodeType != CODE {
+				continue
+			}
+			if lexer := namedLexer(class); lexer != nil {
+				return lexer
+			}
+		}
+	}
+	return nil
+}
+
+// namedLexer returns the lexer with name as its name or an alias. Unlike
+// lexers.Get, it doesn't match file names, so classes that only look like
+// one aren't taken for a language.
+func namedLexer(name string) chroma.Lexer {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil
+	}
+
+	lexer := lexers.Get(name)
+	if lexer == nil || isPlainText(lexer) {
+		return nil
+	}
+	if strings.ToLower(lexer.Config().Name) == name {
+		return lexer
+	}
+	for _, alias := range lexer.Config().Aliases {
+		if alias == name {
+			return lexer
+		}
+	}
+	return nil
+}
+
+func isPlainText(lexer chroma.Lexer) bool {
+	return lexer.Config().Name == "plaintext"
+}
+
+// onlyChild returns the only element below n, ignoring white space around
+// it, or nil.
+func (n *Node) onlyChild() *Node {
+	var only *Node
+	for i := range n.Children {
+		child := &n.Children[i]
+		if child.Element.NodeType == TEXT && strings.TrimSpace(child.InnerText) == "" {
+			continue
+		}
+		if only != nil {
+			return nil
+		}
+		only = child
+	}
+	return only
+}
+
+// preText is the text below n exactly as it was written.
+func (n *Node) preText() string {
+	if len(n.Children) == 0 {
+		return n.InnerText
+	}
+
+	var sb strings.Builder
+	for i := range n.Children {
+		sb.WriteString(n.Children[i].preText())
+	}
+	return sb.String()
+}
+
+// syntaxStyles are the styles of the kinds of tokens, in the colours of the
+// theme.
+type syntaxStyles struct {
+	keyword, str, number, comment, function, typ, operator lipgloss.Style
+}
+
+func newSyntaxStyles() syntaxStyles {
+	theme := config.GetTheme()
+	color := func(c string) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(c))
+	}
+	return syntaxStyles{
+		keyword:  color(theme.SyntaxKeyword),
+		str:      color(theme.SyntaxString),
+		number:   color(theme.SyntaxNumber),
+		comment:  color(theme.SyntaxComment).Italic(true),
+		function: color(theme.SyntaxFunction),
+		typ:      color(theme.SyntaxType),
+		operator: color(theme.SyntaxOperator),
+	}
+}
+
+// of returns the style of a token of type t, or false for tokens shown
+// as they are.
+func (s syntaxStyles) of(t chroma.TokenType) (lipgloss.Style, bool) {
+	switch {
+	case t.InCategory(chroma.Comment):
+		return s.comment, true
+	case t == chroma.KeywordType, t == chroma.NameClass, t == chroma.NameBuiltin, t == chroma.NameTag:
+		return s.typ, true
+	case t.InCategory(chroma.Keyword):
+		return s.keyword, true
+	case t == chroma.NameFunction, t == chroma.NameAttribute, t == chroma.NameDecorator:
+		return s.function, true
+	case t.InSubCategory(chroma.LiteralString):
+		return s.str, true
+	case t.InSubCategory(chroma.LiteralNumber):
+		return s.number, true
+	case t.InCategory(chroma.Operator):
+		return s.operator, true
+	}
+	return lipgloss.Style{}, false
+}
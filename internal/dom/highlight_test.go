@@ -0,0 +1,39 @@
+package dom
+
+import "testing"
+
+func TestCodeLexer(t *testing.T) {
+	pre := func(attrs, codeAttrs map[string]string) *Node {
+		code := Node{Element: ElementData{NodeType: CODE, Name: "code", Attrs: codeAttrs}, Children: []Node{text("x := 1")}}
+		return &Node{Element: ElementData{NodeType: PRE, Name: "pre", Attrs: attrs}, Children: []Node{code}}
+	}
+
+	tests := []struct {
+		name      string
+		attrs     map[string]string
+		codeAttrs map[string]string
+		want      string
+	}{
+		{"prefixed class on pre", map[string]string{"class": "highlight language-go"}, nil, "Go"},
+		{"prefixed class on code", nil, map[string]string{"class": "lang-python"}, "Python"},
+		{"bare class on code", nil, map[string]string{"class": "rust"}, "Rust"},
+		{"data-lang", map[string]string{"data-lang": "JavaScript"}, nil, "JavaScript"},
+		{"bare class on pre", map[string]string{"class": "c"}, nil, ""},
+		{"styling classes on pre", map[string]string{"class": "diff html"}, nil, ""},
+		{"file name", nil, map[string]string{"class": "main.go"}, ""},
+		{"unknown language", nil, map[string]string{"class": "language-nope"}, ""},
+		{"no language", nil, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if lexer := pre(tt.attrs, tt.codeAttrs).codeLexer(); lexer != nil {
+				got = lexer.Config().Name
+			}
+			if got != tt.want {
+				t.Errorf("codeLexer = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
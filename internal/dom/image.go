@@ -0,0 +1,297 @@
+package dom
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"regexp"
+	"runtime"
+	"ruppi/internal/config"
+	"ruppi/pkg/helper"
+	"ruppi/pkg/kitty"
+	"ruppi/pkg/sixel"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// imageMarker is written on every line an inline image occupies, with
+	// the image id and the row. Like the anchor marker it looks like an
+	// escape sequence, so page text can't contain it and word wrapping
+	// leaves it alone. It is swapped for the image escape sequence (first
+	// row) or nothing (other rows) once the final layout is known.
+	imageMarker = "\x1b[%d;%d;7776z"
+
+	// maxImageFetches limits how many images of a page are downloaded at once.
+	maxImageFetches = 8
+)
+
+var imageMarkerPattern = regexp.MustCompile(`\x1b\[(\d+);(\d+);7776z`)
+
+// hasImageMarker reports whether line is a row of an inline image.
+func hasImageMarker(line string) bool {
+	return strings.Contains(line, ";7776z") && imageMarkerPattern.MatchString(line)
+}
+
+// lastImageID hands out ids for inline images. Kitty uses them to replace an
+// image in place, so they are unique across every tab.
+var lastImageID atomic.Int64
+
+// InlineImage is an image drawn in the rendered document.
+type InlineImage struct {
+	ID   int
+	URL  string
+	Alt  string
+	Cols int
+	Rows int
+
+	// Line is the first line the image occupies in the wrapped document,
+	// or -1 if it could not be found.
+	Line int
+
+	anim        *helper.Animation
+	frame       int
+	elapsed     time.Duration
+	pixelWidth  int
+	pixelHeight int
+	isKitty     bool
+	encoded     map[int]string
+}
+
+func newInlineImage(url, alt string, anim *helper.Animation, maxCols int, isKitty bool) *InlineImage {
+	cfg := config.GetSixelConfig()
+	bounds := anim.Frames[0].Bounds()
+
+	maxWidth := cfg.MaxWidth
+	if maxCols > 0 && maxCols*cfg.CellWidth < maxWidth {
+		maxWidth = maxCols * cfg.CellWidth
+	}
+	width, height := fitSize(bounds.Dx(), bounds.Dy(), maxWidth, cfg.MaxHeight)
+
+	return &InlineImage{
+		ID:          int(lastImageID.Add(1)),
+		URL:         url,
+		Alt:         alt,
+		Cols:        max(1, int(math.Ceil(float64(width)/float64(cfg.CellWidth)))),
+		Rows:        max(1, int(math.Ceil(float64(height)/float64(cfg.CellHeight)))),
+		Line:        -1,
+		anim:        anim,
+		pixelWidth:  width,
+		pixelHeight: height,
+		isKitty:     isKitty,
+		encoded:     make(map[int]string),
+	}
+}
+
+// fitSize scales width x height down to fit within maxWidth x maxHeight,
+// keeping the aspect ratio.
+func fitSize(width, height, maxWidth, maxHeight int) (int, int) {
+	if width <= 0 || height <= 0 {
+		return 1, 1
+	}
+
+	scale := 1.0
+	if maxWidth > 0 && width > maxWidth {
+		scale = float64(maxWidth) / float64(width)
+	}
+	if maxHeight > 0 && float64(height)*scale > float64(maxHeight) {
+		scale = float64(maxHeight) / float64(height)
+	}
+
+	return max(1, int(float64(width)*scale)), max(1, int(float64(height)*scale))
+}
+
+// placeholder returns the marker lines reserving room for the image.
+func (img *InlineImage) placeholder() string {
+	rows := make([]string, img.Rows)
+	for r := range rows {
+		rows[r] = fmt.Sprintf(imageMarker, img.ID, r)
+	}
+	return strings.Join(rows, "\n")
+}
+
+// IsAnimated reports whether the image has frames to play.
+func (img *InlineImage) IsAnimated() bool {
+	return config.GetSixelConfig().Animate && img.anim.IsAnimated()
+}
+
+// Animation returns the decoded frames of the image.
+func (img *InlineImage) Animation() *helper.Animation {
+	return img.anim
+}
+
+// CurrentFrame returns the frame being displayed.
+func (img *InlineImage) CurrentFrame() image.Image {
+	return img.anim.Frames[img.frame]
+}
+
+// Visible reports whether any row of the image is between the lines top and
+// top+height.
+func (img *InlineImage) Visible(top, height int) bool {
+	return img.Line >= 0 && img.Line < top+height && img.Line+img.Rows > top
+}
+
+// Advance moves the animation forward by elapsed and reports whether the
+// displayed frame changed.
+func (img *InlineImage) Advance(elapsed time.Duration) bool {
+	if !img.IsAnimated() {
+		return false
+	}
+
+	changed := false
+	img.elapsed += elapsed
+	for img.elapsed >= img.anim.Delays[img.frame] {
+		img.elapsed -= img.anim.Delays[img.frame]
+		img.frame = (img.frame + 1) % len(img.anim.Frames)
+		changed = true
+	}
+	return changed
+}
+
+// SixelEncoder returns an encoder for images of at most width x height pixels
+// with the configured palette, dithering and parallelism.
+func SixelEncoder(width, height int) *sixel.Encoder {
+	cfg := config.GetSixelConfig()
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	return &sixel.Encoder{
+		Width:   width,
+		Height:  height,
+		Colors:  cfg.Colors,
+		Dither:  cfg.Dither == "floyd-steinberg",
+		Ordered: cfg.Dither == "ordered",
+		Workers: workers,
+	}
+}
+
+// Sequence returns the escape sequence that draws the current frame.
+// Encoded frames are kept, so a looping animation is only encoded once.
+func (img *InlineImage) Sequence() (string, error) {
+	if seq, ok := img.encoded[img.frame]; ok {
+		return seq, nil
+	}
+
+	var (
+		seq string
+		err error
+	)
+	frame := img.anim.Frames[img.frame]
+	if img.isKitty {
+		seq, err = kitty.Encode(frame, img.ID, img.Cols, img.Rows, img.pixelWidth, img.pixelHeight)
+	} else {
+		seq, err = SixelEncoder(img.pixelWidth, img.pixelHeight).EncodeToString(frame)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	img.encoded[img.frame] = seq
+	return seq, nil
+}
+
+// LocateImages records the line each image starts on in wrapped text.
+func LocateImages(text string, images []*InlineImage) {
+	byID := make(map[int]*InlineImage, len(images))
+	for _, img := range images {
+		img.Line = -1
+		byID[img.ID] = img
+	}
+
+	for i, line := range strings.Split(text, "\n") {
+		if !hasImageMarker(line) {
+			continue
+		}
+
+		for _, m := range imageMarkerPattern.FindAllStringSubmatch(line, -1) {
+			if m[2] != "0" {
+				continue
+			}
+			id, err := strconv.Atoi(m[1])
+			if err != nil {
+				continue
+			}
+			if img, ok := byID[id]; ok && img.Line == -1 {
+				img.Line = i
+			}
+		}
+	}
+}
+
+// ExpandImages replaces the image markers in wrapped text with the escape
+// sequences drawing the current frame of every image.
+func ExpandImages(text string, images []*InlineImage) string {
+	return ExpandImageLabels(text, images, nil)
+}
+
+// ExpandImageLabels is like ExpandImages, but draws the given label instead
+// of the image for every image in labels. It is used to show hint labels
+// for picking an image with the keyboard.
+func ExpandImageLabels(text string, images []*InlineImage, labels map[*InlineImage]string) string {
+	if len(images) == 0 {
+		return text
+	}
+
+	var pairs []string
+	for _, img := range images {
+		seq, err := img.Sequence()
+		if err != nil {
+			seq = ItalicStyle.Render(fmt.Sprintf("[Image: %s]", img.Alt))
+		}
+		if label, ok := labels[img]; ok {
+			seq = label
+		}
+
+		pairs = append(pairs, fmt.Sprintf(imageMarker, img.ID, 0), seq)
+		for r := 1; r < img.Rows; r++ {
+			pairs = append(pairs, fmt.Sprintf(imageMarker, img.ID, r), "")
+		}
+	}
+
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// prefetchImages downloads the images of a document concurrently, so the
+// renderer only has to read them from the cache.
+func prefetchImages(n *Node, baseURL string, maxWidth, viewportWidth int) {
+	var urls []string
+	var collect func(n *Node)
+	collect = func(n *Node) {
+		if n.Element.NodeType == PICTURE {
+			if img, ok := n.resolvePicture(viewportWidth); ok {
+				collect(&img)
+				return
+			}
+		}
+
+		if n.Element.NodeType == IMG {
+			if src := imageSource(n.Element.Attrs, maxWidth, viewportWidth); src != "" {
+				if resolved, err := helper.ResolveURL(baseURL, src); err == nil {
+					urls = append(urls, resolved)
+				}
+			}
+		}
+		for i := range n.Children {
+			collect(&n.Children[i])
+		}
+	}
+	collect(n)
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxImageFetches)
+	for _, url := range urls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(url string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			helper.AnimationFromURL(url)
+		}(url)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,33 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandImagesTallImage(t *testing.T) {
+	img := &InlineImage{ID: 1, Rows: 15, encoded: map[int]string{0: "SEQ"}}
+	text := img.placeholder()
+
+	LocateImages("before\n"+text, []*InlineImage{img})
+	if img.Line != 1 {
+		t.Errorf("image located on line %d, want 1", img.Line)
+	}
+
+	got := ExpandImages(text, []*InlineImage{img})
+	want := "SEQ" + strings.Repeat("\n", img.Rows-1)
+	if got != want {
+		t.Errorf("ExpandImages = %q, want %q", got, want)
+	}
+}
+
+func TestImageMarkerIsNotText(t *testing.T) {
+	for _, line := range []string{"img:1:0", "see img:2:10 here", "\x1b[1;0m"} {
+		if hasImageMarker(line) {
+			t.Errorf("hasImageMarker(%q) = true", line)
+		}
+	}
+	if !hasImageMarker("  " + (&InlineImage{ID: 3, Rows: 1}).placeholder()) {
+		t.Error("hasImageMarker misses an image row")
+	}
+}
@@ -0,0 +1,344 @@
+package dom
+
+import (
+	"ruppi/internal/config"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/wrap"
+)
+
+// The page is laid out the way CSS lays out normal flow. Block boxes are
+// stacked one below the other, and the vertical margins between them
+// collapse into the largest one. The inline content between blocks flows
+// into lines, with runs of white space collapsed into a single space.
+
+// box is a laid out block: its lines, and the margins above and below it.
+type box struct {
+	text        string
+	top, bottom int
+}
+
+// inline is laid out inline content: its text, with white space collapsed,
+// and whether it had white space before or after it, which becomes a single
+// space between it and the content next to it.
+type inline struct {
+	text                    string
+	spaceBefore, spaceAfter bool
+}
+
+// collapseSpace lays out text outside of preformatted elements: runs of
+// white space become a single space, and white space at either end becomes
+// the space before or after the text.
+func collapseSpace(text string) inline {
+	fields := strings.FieldsFunc(text, isCollapsibleSpace)
+	if len(fields) == 0 {
+		return inline{spaceBefore: text != "", spaceAfter: text != ""}
+	}
+
+	first, _ := utf8.DecodeRuneInString(text)
+	last, _ := utf8.DecodeLastRuneInString(text)
+	return inline{
+		text:        strings.Join(fields, " "),
+		spaceBefore: isCollapsibleSpace(first),
+		spaceAfter:  isCollapsibleSpace(last),
+	}
+}
+
+// isCollapsibleSpace reports whether r is white space that collapses. A
+// no-break space is kept.
+func isCollapsibleSpace(r rune) bool {
+	return r != '\u00a0' && unicode.IsSpace(r)
+}
+
+// append lays out next after c. The white space between them becomes one
+// space, unless either side has nothing to show or a line ends there.
+func (c inline) append(next inline) inline {
+	if !isVisible(c.text) {
+		return inline{
+			text:        c.text + next.text,
+			spaceBefore: c.spaceBefore || c.spaceAfter || next.spaceBefore,
+			spaceAfter:  next.spaceAfter || !isVisible(next.text) && (c.spaceAfter || next.spaceBefore),
+		}
+	}
+	if !isVisible(next.text) {
+		c.text += next.text
+		c.spaceAfter = c.spaceAfter || next.spaceBefore || next.spaceAfter
+		return c
+	}
+
+	space := ""
+	if (c.spaceAfter || next.spaceBefore) && !strings.HasSuffix(c.text, "\n") && !strings.HasPrefix(next.text, "\n") {
+		space = " "
+	}
+	c.text += space + next.text
+	c.spaceAfter = next.spaceAfter
+	return c
+}
+
+// isVisible reports whether text shows anything, rather than only holding
+// escape sequences such as anchor markers. The rows of an image show it.
+func isVisible(text string) bool {
+	return strings.TrimSpace(stripANSICodes(text)) != "" || hasImageMarker(text)
+}
+
+// flow is a block formatting context: it stacks blocks and collapses the
+// margins between them. Margins before its first block and after its last
+// one are kept apart, for the box around the flow to collapse with its own
+// margins or to leave out.
+type flow struct {
+	sb      strings.Builder
+	started bool
+
+	// leading is the margin before the first block, and margin the one
+	// waiting to be written before the next block.
+	leading int
+	margin  int
+	// markers are escape sequences of blocks that showed nothing, which go
+	// in front of the next block.
+	markers string
+}
+
+// add lays out b below the blocks so far.
+func (f *flow) add(b box) {
+	if !isVisible(b.text) {
+		// an empty block's margins collapse through it
+		f.markers += b.text
+		f.margin = max(f.margin, b.top, b.bottom)
+		return
+	}
+
+	if !f.started {
+		f.leading = max(f.margin, b.top)
+		f.started = true
+	} else {
+		f.sb.WriteString(strings.Repeat("\n", 1+max(f.margin, b.top)))
+	}
+	f.sb.WriteString(f.markers)
+	f.sb.WriteString(strings.Trim(b.text, "\n"))
+	f.markers = ""
+	f.margin = b.bottom
+}
+
+// box returns the blocks laid out so far as one box, with the margins at
+// its edges.
+func (f *flow) box() box {
+	if !f.started {
+		return box{text: f.markers, top: f.margin, bottom: f.margin}
+	}
+	return box{text: f.sb.String() + f.markers, top: f.leading, bottom: f.margin}
+}
+
+// isBlockLevel reports whether n is laid out as a block. An inline element
+// holding blocks, such as a link around a whole card, is one too.
+func (n *Node) isBlockLevel() bool {
+	return isBlockElement(n.Element.NodeType) || n.hasBlock()
+}
+
+func (n *Node) hasBlock() bool {
+	for i := range n.Children {
+		child := &n.Children[i]
+		if isBlockElement(child.Element.NodeType) || child.hasBlock() {
+			return true
+		}
+	}
+	return false
+}
+
+// layoutBlock lays out the block n: its children, then the way the element
+// is shown around them.
+func (n *Node) layoutBlock(state *renderState, url string, isKitty bool) box {
+	if n.Element.NodeType == DETAILS {
+		return n.layoutDetails(state, url, isKitty)
+	}
+
+	content := n.layoutChildren(state.forChildren(n), url, isKitty)
+	text, top, bottom := n.decorate(state, content.text, url, isKitty)
+	if !n.isBoxed() {
+		// the margins of the first and last blocks inside collapse
+		// with the block's own
+		top = max(top, content.top)
+		bottom = max(bottom, content.bottom)
+	}
+	return box{text: n.markAnchor(state, text), top: top, bottom: bottom}
+}
+
+// isBoxed reports whether n is drawn around its content, with a border,
+// a marker or indentation, which keeps the margins inside it apart from
+// those outside.
+func (n *Node) isBoxed() bool {
+	switch n.Element.NodeType {
+	case LI, DD, BLOCKQUOTE, PRE, TEXTAREA, DETAILS:
+		return true
+	}
+	return false
+}
+
+// layoutChildren lays out the children of n, which are laid out in state:
+// blocks one below the other and the inline content between them in lines.
+func (n *Node) layoutChildren(state *renderState, url string, isKitty bool) box {
+	if len(n.Children) == 0 {
+		if state.preformatted {
+			return box{text: n.InnerText}
+		}
+		return box{text: collapseSpace(n.InnerText).text}
+	}
+
+	var f flow
+	var line inline
+	endLine := func() {
+		if line.text != "" {
+			f.add(box{text: state.orderLines(state.breakLines(line.text))})
+		}
+		line = inline{}
+	}
+
+	for i := range n.Children {
+		child := &n.Children[i]
+		if child.isBlockLevel() {
+			endLine()
+			f.add(child.layoutBlock(state, url, isKitty))
+			continue
+		}
+		if state.preformatted {
+			line.text += child.layoutInline(state, url, isKitty).text
+		} else {
+			line = line.append(child.layoutInline(state, url, isKitty))
+		}
+	}
+	endLine()
+	return f.box()
+}
+
+// layoutInline lays out the inline element or text n.
+func (n *Node) layoutInline(state *renderState, url string, isKitty bool) inline {
+	switch n.Element.NodeType {
+	case TEXT:
+		if state.preformatted {
+			return inline{text: n.InnerText}
+		}
+		return collapseSpace(n.InnerText)
+	case PICTURE:
+		if img, ok := n.resolvePicture(state.viewportWidth()); ok {
+			return img.layoutInline(state, url, isKitty)
+		}
+	}
+
+	childState := state.forChildren(n)
+	var content inline
+	if len(n.Children) == 0 && !childState.preformatted {
+		content = collapseSpace(n.InnerText)
+	} else {
+		for i := range n.Children {
+			next := n.Children[i].layoutInline(childState, url, isKitty)
+			if childState.preformatted {
+				content.text += next.text
+			} else {
+				content = content.append(next)
+			}
+		}
+	}
+
+	text, _, _ := n.decorate(state, content.text, url, isKitty)
+	content.text = n.markAnchor(state, text)
+	if n.isTableCell() {
+		// the cells of a row are set apart like words
+		content.spaceBefore, content.spaceAfter = true, true
+	}
+	return content
+}
+
+// isTableCell reports whether n is a cell of a table, which is laid out
+// inline in its row.
+func (n *Node) isTableCell() bool {
+	return n.Element.Name == "td" || n.Element.Name == "th"
+}
+
+// breakLines breaks the lines of inline content that run past the line
+// width at spaces, filling each line with as many words as fit. Only a word
+// wider than a line is broken inside.
+func (s *renderState) breakLines(text string) string {
+	if s.preformatted || s.lineWidth <= 0 {
+		return text
+	}
+
+	var sb strings.Builder
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			sb.WriteRune('\n')
+		}
+
+		length := 0
+		for j, word := range strings.Split(line, " ") {
+			width := lipgloss.Width(word)
+			switch {
+			case j == 0:
+			case length > 0 && length+1+width > s.lineWidth:
+				sb.WriteRune('\n')
+				length = 0
+			default:
+				sb.WriteRune(' ')
+				length++
+			}
+
+			if length+width > s.lineWidth {
+				word = wrap.String(word, s.lineWidth)
+				width = lipgloss.Width(word[strings.LastIndex(word, "\n")+1:])
+				length = 0
+			}
+			sb.WriteString(word)
+			length += width
+		}
+	}
+	return sb.String()
+}
+
+// orderLines puts the lines of a paragraph in the order they are shown in,
+// aligning right-to-left ones to the right.
+func (s *renderState) orderLines(text string) string {
+	if s.preformatted {
+		return orderLines(text, s.dir, 0)
+	}
+	return orderLines(text, s.dir, s.lineWidth)
+}
+
+// forChildren returns the state the children of n are laid out in.
+func (s *renderState) forChildren(n *Node) *renderState {
+	child := *s
+	if dir, ok := parseDirection(n.Element.Attrs["dir"]); ok {
+		child.dir = dir
+	}
+	switch n.Element.NodeType {
+	case PRE, TEXTAREA:
+		child.preformatted = true
+	case UL, OL:
+		list := n.newListContext(len(s.lists), s.inItem)
+		child.lists = append(s.lists[:len(s.lists):len(s.lists)], list)
+		child.inItem = false
+	case LI:
+		child.inItem = true
+		list := s.currentList()
+		child.indent(lipgloss.Width(list.itemPrefix(list.itemNumber(n))))
+	case DD:
+		child.indent(2*config.GetListConfig().Indent + config.FrameWidth(n.Element.Name))
+	case BLOCKQUOTE:
+		child.indent(BlockquoteStyle.GetHorizontalFrameSize())
+	default:
+		if isBlockElement(n.Element.NodeType) {
+			child.indent(config.FrameWidth(n.Element.Name))
+		}
+	}
+	return &child
+}
+
+// minLineWidth is the narrowest lines get when boxes are nested deep.
+const minLineWidth = 20
+
+// indent narrows the lines by width columns.
+func (s *renderState) indent(width int) {
+	if width > 0 && s.lineWidth > 0 {
+		s.lineWidth = max(s.lineWidth-width, min(s.lineWidth, minLineWidth))
+	}
+}
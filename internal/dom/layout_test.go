@@ -0,0 +1,185 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func text(s string) Node {
+	return Node{Element: ElementData{NodeType: TEXT}, InnerText: s}
+}
+
+func element(name string, children ...Node) Node {
+	return Node{Element: ElementData{NodeType: TagToType[name], Name: name, Attrs: map[string]string{}}, Children: children}
+}
+
+// tableRow is a row of cells the way the parser reads tables: the row as a
+// block and its cells as inline elements.
+func tableRow(cells ...string) Node {
+	row := Node{Element: ElementData{NodeType: DIV, Name: "tr", Attrs: map[string]string{}}}
+	for _, cell := range cells {
+		name := "td"
+		if strings.HasPrefix(cell, "#") {
+			name, cell = "th", cell[1:]
+		}
+		row.Children = append(row.Children, Node{
+			Element:  ElementData{NodeType: SPAN, Name: name, Attrs: map[string]string{}},
+			Children: []Node{text(cell)},
+		})
+	}
+	return row
+}
+
+// layout lays out n as the whole page, width columns wide, with the escape
+// sequences taken out.
+func layout(n Node, width int) string {
+	state := &renderState{width: width, lineWidth: width, page: &Page{}}
+	return stripANSICodes(n.layoutBlock(state, "", false).text)
+}
+
+func TestLayoutInlineSpacing(t *testing.T) {
+	tests := []struct {
+		name string
+		node Node
+		want string
+	}{
+		{
+			name: "no space around inline element",
+			node: element("p", text("foo"), element("b", text("bar")), text("baz")),
+			want: "foobarbaz",
+		},
+		{
+			name: "punctuation around link",
+			node: element("p", text("("), element("a", text("q")), text(")")),
+			want: "(q)",
+		},
+		{
+			name: "space inside inline element",
+			node: element("p", text("foo"), element("b", text(" bar ")), text("baz")),
+			want: "foo bar baz",
+		},
+		{
+			name: "runs of space collapse",
+			node: element("p", text("  foo \n\t bar  "), text(" "), element("i", text(" baz"))),
+			want: "foo bar baz",
+		},
+		{
+			name: "no-break space is kept",
+			node: element("p", text("foo\u00a0\u00a0  bar")),
+			want: "foo\u00a0\u00a0 bar",
+		},
+		{
+			name: "table cells",
+			node: tableRow("a", "b", "(c)"),
+			want: "a b (c)",
+		},
+		{
+			name: "table header and empty cell",
+			node: tableRow("#Name", "", "#Value"),
+			want: "Name Value",
+		},
+		{
+			name: "empty inline element",
+			node: element("p", text("foo "), element("span"), text(" bar")),
+			want: "foo bar",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := layout(tt.node, 80); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlowCollapsesMargins(t *testing.T) {
+	tests := []struct {
+		name  string
+		boxes []box
+		want  box
+	}{
+		{
+			name:  "largest margin between blocks",
+			boxes: []box{{text: "a", bottom: 1}, {text: "b", top: 2}},
+			want:  box{text: "a\n\n\nb"},
+		},
+		{
+			name:  "through empty block",
+			boxes: []box{{text: "a", bottom: 1}, {top: 3, bottom: 1}, {text: "b", top: 1}},
+			want:  box{text: "a\n\n\n\nb"},
+		},
+		{
+			name:  "empty block keeps its markers",
+			boxes: []box{{text: "a"}, {text: fmt.Sprintf(anchorMarker, 0), top: 1, bottom: 1}, {text: "b"}},
+			want:  box{text: "a\n\n" + fmt.Sprintf(anchorMarker, 0) + "b"},
+		},
+		{
+			name:  "edge margins are kept apart",
+			boxes: []box{{top: 2}, {text: "a", top: 1, bottom: 1}, {bottom: 3}},
+			want:  box{text: "a", top: 2, bottom: 3},
+		},
+		{
+			name:  "only empty blocks",
+			boxes: []box{{top: 1, bottom: 2}, {top: 1}},
+			want:  box{top: 2, bottom: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f flow
+			for _, b := range tt.boxes {
+				f.add(b)
+			}
+			if got := f.box(); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkersHaveNoWidth(t *testing.T) {
+	anchor := fmt.Sprintf(anchorMarker, 12)
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "anchor between words",
+			text: "foo " + anchor + "bar baz",
+			want: "foo " + anchor + "bar\nbaz",
+		},
+		{
+			name: "anchor at line end",
+			text: "foo bar" + anchor + " baz",
+			want: "foo bar" + anchor + "\nbaz",
+		},
+		{
+			name: "no-wrap markers",
+			text: noWrapStart + "foo" + noWrapEnd + " bar baz",
+			want: noWrapStart + "foo" + noWrapEnd + " bar\nbaz",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &renderState{lineWidth: 7}
+			if got := state.breakLines(tt.text); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// an inline element holding only an anchor takes no space of its own
+	got := inline{text: "foo"}.append(inline{text: anchor}).append(inline{text: "bar"})
+	if got.text != "foo"+anchor+"bar" {
+		t.Errorf("append = %q, want the anchor between the words", got.text)
+	}
+	if isVisible(anchor + noWrapStart + noWrapEnd) {
+		t.Error("markers count as visible text")
+	}
+}
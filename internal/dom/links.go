@@ -0,0 +1,44 @@
+package dom
+
+import (
+	"fmt"
+	"ruppi/pkg/helper"
+	"strconv"
+	"strings"
+)
+
+// linkReference returns the number of the reference to href, resolved
+// against base, in the list at the end of the page. Links to the same URL
+// share a number.
+func (s *renderState) linkReference(base, href string) int {
+	if resolved, err := helper.ResolveURL(base, href); err == nil {
+		href = resolved
+	}
+
+	if number, ok := s.page.linkNumbers[href]; ok {
+		return number
+	}
+	if s.page.linkNumbers == nil {
+		s.page.linkNumbers = map[string]int{}
+	}
+	s.page.Links = append(s.page.Links, href)
+	s.page.linkNumbers[href] = len(s.page.Links)
+	return len(s.page.Links)
+}
+
+// references is the numbered list of the links of the page, which ends it.
+func (s *renderState) references() string {
+	if len(s.page.Links) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n" + HrStyle.Render(strings.Repeat("─", 50)) + "\n")
+	sb.WriteString(BoldStyle.Render("References") + "\n\n")
+
+	digits := len(strconv.Itoa(len(s.page.Links)))
+	for i, link := range s.page.Links {
+		fmt.Fprintf(&sb, "%*d. %s\n", digits+2, i+1, link)
+	}
+	return sb.String()
+}
@@ -0,0 +1,64 @@
+package dom
+
+import (
+	"ruppi/internal/config"
+	"strings"
+	"testing"
+)
+
+func anchor(href, s string) Node {
+	return withAttr(element("a", text(s)), "href", href)
+}
+
+func setLinkDisplay(t *testing.T, display string) {
+	t.Helper()
+	if err := config.Set("link-display", display); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.Set("link-display", config.LINK_DISPLAY_INLINE) })
+}
+
+func TestFootnoteLinks(t *testing.T) {
+	setLinkDisplay(t, config.LINK_DISPLAY_FOOTNOTE)
+
+	doc := element("div",
+		element("p", anchor("/docs", "docs"), text(" and "), anchor("https://go.dev/", "Go")),
+		element("p", anchor("https://example.com/docs", "the docs again"), text(" and "), element("a", text("no href"))),
+	)
+	page := doc.Render("https://example.com/page", 80, false)
+	content := stripANSICodes(page.Content)
+
+	for _, want := range []string{"docs[1]", "Go[2]", "the docs again[1]", "no href"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content is missing %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "[3]") {
+		t.Errorf("content has a third reference:\n%s", content)
+	}
+
+	if want := []string{"https://example.com/docs", "https://go.dev/"}; strings.Join(page.Links, " ") != strings.Join(want, " ") {
+		t.Errorf("links %q, want %q", page.Links, want)
+	}
+	_, references, ok := strings.Cut(content, "References")
+	if !ok {
+		t.Fatalf("no references at the end of the page:\n%s", content)
+	}
+	if want := "\n\n  1. https://example.com/docs\n  2. https://go.dev/\n"; references != want {
+		t.Errorf("references %q, want %q", references, want)
+	}
+}
+
+func TestInlineLinks(t *testing.T) {
+	setLinkDisplay(t, config.LINK_DISPLAY_INLINE)
+
+	doc := element("p", anchor("https://go.dev/", "Go"))
+	page := doc.Render("", 80, false)
+	content := stripANSICodes(page.Content)
+	if !strings.Contains(content, "Go https://go.dev/") {
+		t.Errorf("content is missing the URL after the link:\n%s", content)
+	}
+	if strings.Contains(content, "References") || len(page.Links) > 0 {
+		t.Errorf("inline links got references:\n%s", content)
+	}
+}
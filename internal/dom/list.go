@@ -0,0 +1,188 @@
+package dom
+
+import (
+	"ruppi/internal/config"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// listContext is a list being rendered: how its items are marked and the
+// number of the next one.
+type listContext struct {
+	ordered bool
+	// numbering is the type of an ordered list: "1", "a", "A", "i" or "I".
+	numbering string
+	bullet    string
+	next      int
+	step      int
+	indent    int
+}
+
+// bulletTypes are the bullets of the type attribute of <ul>.
+var bulletTypes = map[string]string{"disc": "•", "circle": "◦", "square": "▪"}
+
+// newListContext starts the list n, which is nested depth lists deep. A list
+// put straight inside another one, rather than in one of its items, is
+// indented one more level.
+func (n *Node) newListContext(depth int, inItem bool) *listContext {
+	lists := config.GetListConfig()
+	list := &listContext{
+		ordered: n.Element.NodeType == OL,
+		next:    1,
+		step:    1,
+		indent:  lists.Indent,
+	}
+	if depth > 0 && !inItem {
+		list.indent += lists.Indent
+	}
+
+	attrs := n.Element.Attrs
+	if !list.ordered {
+		list.bullet = lists.Bullets[depth%len(lists.Bullets)]
+		if bullet, ok := bulletTypes[strings.ToLower(attrs["type"])]; ok {
+			list.bullet = bullet
+		}
+		return list
+	}
+
+	list.numbering = "1"
+	switch numbering := attrs["type"]; numbering {
+	case "a", "A", "i", "I":
+		list.numbering = numbering
+	}
+
+	if _, ok := attrs["reversed"]; ok {
+		list.step = -1
+		list.next = n.countItems()
+	}
+	if start, err := strconv.Atoi(strings.TrimSpace(attrs["start"])); err == nil {
+		list.next = start
+	}
+	return list
+}
+
+// countItems is how many items list n has.
+func (n *Node) countItems() int {
+	count := 0
+	for _, child := range n.Children {
+		if child.Element.NodeType == LI {
+			count++
+		}
+	}
+	return count
+}
+
+// listItem renders the item n of the innermost list with content: its
+// marker, then content with every line after the first lined up with the
+// text of the first one, so lists nested in the item sit one level deeper.
+func (s *renderState) listItem(n *Node, content string) string {
+	list := s.currentList()
+	number := list.itemNumber(n)
+	if list.ordered {
+		list.next = number + list.step
+	}
+
+	prefix := list.itemPrefix(number)
+	return prefix + hangingIndent(content, lipgloss.Width(prefix))
+}
+
+// itemNumber is the number of the item n: its value, or the one after the
+// item before it.
+func (l *listContext) itemNumber(n *Node) int {
+	if value, err := strconv.Atoi(strings.TrimSpace(n.Element.Attrs["value"])); err == nil && l.ordered {
+		return value
+	}
+	return l.next
+}
+
+// itemPrefix is what goes before the first line of the item numbered
+// number: the indentation of the list and the item's marker.
+func (l *listContext) itemPrefix(number int) string {
+	marker := l.bullet
+	if l.ordered {
+		marker = listNumber(number, l.numbering) + "."
+	}
+	return strings.Repeat(" ", l.indent) + marker + " "
+}
+
+// currentList returns the innermost list, or a list of its own for an item
+// outside of any.
+func (s *renderState) currentList() *listContext {
+	if len(s.lists) == 0 {
+		lists := config.GetListConfig()
+		return &listContext{bullet: lists.Bullets[0], indent: lists.Indent}
+	}
+	return s.lists[len(s.lists)-1]
+}
+
+// hangingIndent indents every line of text after the first by width.
+func hangingIndent(text string, width int) string {
+	if !strings.Contains(text, "\n") {
+		return text
+	}
+
+	indent := strings.Repeat(" ", width)
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		if isVisible(lines[i]) {
+			lines[i] = indent + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// listNumber writes number the way an ordered list of the given type
+// numbers its items. Numbers that type can't show are written in decimal.
+func listNumber(number int, numbering string) string {
+	switch numbering {
+	case "a", "A":
+		if number > 0 {
+			letters := alphabetic(number)
+			if numbering == "A" {
+				letters = strings.ToUpper(letters)
+			}
+			return letters
+		}
+	case "i", "I":
+		if number > 0 && number < 4000 {
+			numeral := roman(number)
+			if numbering == "i" {
+				numeral = strings.ToLower(numeral)
+			}
+			return numeral
+		}
+	}
+	return strconv.Itoa(number)
+}
+
+// alphabetic numbers like spreadsheet columns: a to z, then aa, ab and on.
+func alphabetic(number int) string {
+	var letters []byte
+	for number > 0 {
+		number--
+		letters = append([]byte{byte('a' + number%26)}, letters...)
+		number /= 26
+	}
+	return string(letters)
+}
+
+var romanNumerals = []struct {
+	value   int
+	numeral string
+}{
+	{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"}, {100, "C"}, {90, "XC"},
+	{50, "L"}, {40, "XL"}, {10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+}
+
+func roman(number int) string {
+	var sb strings.Builder
+	for _, r := range romanNumerals {
+		for number >= r.value {
+			sb.WriteString(r.numeral)
+			number -= r.value
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,66 @@
+package dom
+
+import "testing"
+
+func withAttr(n Node, key, value string) Node {
+	n.Element.Attrs[key] = value
+	return n
+}
+
+func TestListNumber(t *testing.T) {
+	tests := []struct {
+		number    int
+		numbering string
+		want      string
+	}{
+		{1, "1", "1"},
+		{-2, "1", "-2"},
+		{1, "a", "a"},
+		{26, "a", "z"},
+		{27, "a", "aa"},
+		{28, "A", "AB"},
+		{702, "a", "zz"},
+		{703, "a", "aaa"},
+		{0, "a", "0"},
+		{1, "i", "i"},
+		{4, "i", "iv"},
+		{9, "I", "IX"},
+		{14, "i", "xiv"},
+		{1994, "I", "MCMXCIV"},
+		{3999, "I", "MMMCMXCIX"},
+		{4000, "I", "4000"},
+		{0, "i", "0"},
+	}
+	for _, tt := range tests {
+		if got := listNumber(tt.number, tt.numbering); got != tt.want {
+			t.Errorf("listNumber(%d, %q) = %q, want %q", tt.number, tt.numbering, got, tt.want)
+		}
+	}
+}
+
+func TestOrderedListNumbering(t *testing.T) {
+	items := func() []Node {
+		return []Node{element("li", text("x")), element("li", text("y")), element("li", text("z"))}
+	}
+	tests := []struct {
+		name string
+		list Node
+		want string
+	}{
+		{"decimal", element("ol", items()...), "  1. x\n  2. y\n  3. z"},
+		{"start", withAttr(element("ol", items()...), "start", "5"), "  5. x\n  6. y\n  7. z"},
+		{"reversed", withAttr(element("ol", items()...), "reversed", ""), "  3. x\n  2. y\n  1. z"},
+		{"reversed with start", withAttr(withAttr(element("ol", items()...), "reversed", ""), "start", "10"), "  10. x\n  9. y\n  8. z"},
+		{"reversed past zero", withAttr(withAttr(element("ol", items()...), "reversed", ""), "start", "1"), "  1. x\n  0. y\n  -1. z"},
+		{"type", withAttr(element("ol", items()...), "type", "i"), "  i. x\n  ii. y\n  iii. z"},
+		{"value", element("ol", element("li", text("x")), withAttr(element("li", text("y")), "value", "7"), element("li", text("z"))), "  1. x\n  7. y\n  8. z"},
+		{"value in reversed", withAttr(element("ol", withAttr(element("li", text("x")), "value", "4"), element("li", text("y"))), "reversed", ""), "  4. x\n  3. y"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := layout(tt.list, 40); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
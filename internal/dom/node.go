@@ -2,8 +2,10 @@ package dom
 
 import (
 	"fmt"
+	"image"
 	"ruppi/internal/config"
 	"ruppi/pkg/helper"
+	"ruppi/pkg/svg"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
@@ -30,6 +32,13 @@ const (
 	BLOCKQUOTE
 	BR
 	HR
+	DL
+	DT
+	DD
+	DETAILS
+	SUMMARY
+	FIGURE
+	FIGCAPTION
 
 	SPAN
 	A
@@ -39,12 +48,23 @@ const (
 	EM
 	CODE
 	IMG
+	SVG
+	PICTURE
+	SOURCE
+	MARK
+	KBD
+	SUB
+	SUP
+	ABBR
+	DEL
+	INS
 
 	STYLE
 	SCRIPT
 	IFRAME
 
 	INPUT
+	TEXTAREA
 )
 
 var (
@@ -54,6 +74,17 @@ var (
 	LinkStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("32")).Underline(true)
 	HrStyle         = lipgloss.NewStyle().Faint(true)
 
+	// Default styles of the tags below, used unless the config has a
+	// section for them.
+	TermStyle    = lipgloss.NewStyle().Bold(true)
+	SummaryStyle = lipgloss.NewStyle().Bold(true)
+	CaptionStyle = lipgloss.NewStyle().Italic(true).Faint(true)
+	MarkStyle    = lipgloss.NewStyle().Background(lipgloss.Color("11")).Foreground(lipgloss.Color("0"))
+	KbdStyle     = lipgloss.NewStyle().Background(lipgloss.Color("#3e4451")).Foreground(lipgloss.Color("#e5e5e5"))
+	AbbrStyle    = lipgloss.NewStyle().Underline(true)
+	DelStyle     = lipgloss.NewStyle().Strikethrough(true).Faint(true)
+	InsStyle     = lipgloss.NewStyle().Underline(true)
+
 	InputStyle           = lipgloss.NewStyle().Background(lipgloss.Color("#242424")).Faint(true)
 	InputBackgroundStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#242424"))
 
@@ -78,6 +109,13 @@ var TagToType = map[string]uint{
 	"blockquote": BLOCKQUOTE,
 	"br":         BR,
 	"hr":         HR,
+	"dl":         DL,
+	"dt":         DT,
+	"dd":         DD,
+	"details":    DETAILS,
+	"summary":    SUMMARY,
+	"figure":     FIGURE,
+	"figcaption": FIGCAPTION,
 	"span":       SPAN,
 	"a":          A,
 	"b":          BOLD,
@@ -86,10 +124,24 @@ var TagToType = map[string]uint{
 	"em":         EM,
 	"code":       CODE,
 	"img":        IMG,
+	"svg":        SVG,
+	"picture":    PICTURE,
+	"source":     SOURCE,
+	"mark":       MARK,
+	"kbd":        KBD,
+	"sub":        SUB,
+	"sup":        SUP,
+	"abbr":       ABBR,
+	"del":        DEL,
+	"s":          DEL,
+	"strike":     DEL,
+	"ins":        INS,
 	"style":      STYLE,
 	"script":     SCRIPT,
 	"iframe":     IFRAME,
 	"input":      INPUT,
+	"textarea":   TEXTAREA,
+	"listing":    PRE,
 }
 
 type Node struct {
@@ -103,122 +155,204 @@ type ElementData struct {
 	Attrs    map[string]string
 }
 
+// Page is the result of rendering a document. Anchors are the names of the
+// anchors marked in Content, see LocateAnchors, and Headings the outline of
+// the document. Links are the URLs of the references at the end of the page
+// when links are shown as footnotes.
+type Page struct {
+	Content  string
+	Images   []*InlineImage
+	Anchors  []string
+	Headings []Heading
+	Links    []string
+	Details  []Details
+
+	linkNumbers map[string]int
+}
+
 type renderState struct {
-	builder   *strings.Builder
-	listIndex int
+	width int
+	page  *Page
+	// lineWidth is the width lines of text are broken at: the width of
+	// the page, less the indentation of the boxes around them.
+	lineWidth int
+
+	// lists are the lists being rendered, innermost last, and inItem is
+	// set inside an item of the innermost one.
+	lists  []*listContext
+	inItem bool
+
+	// preformatted is set inside PRE and TEXTAREA, where white space is
+	// kept as it is.
+	preformatted bool
+	// dir is the direction set by the dir attribute of the closest element
+	// with one.
+	dir direction
 }
 
-func (s *renderState) ensureNewline() {
-	if s.builder.Len() > 0 && s.builder.String()[s.builder.Len()-1] != '\n' {
-		s.builder.WriteRune('\n')
-	}
+// viewportWidth is the width of the page in pixels, used to evaluate srcset
+// and media conditions the way a browser window of that size would.
+func (s *renderState) viewportWidth() int {
+	return s.width * config.GetSixelConfig().CellWidth
 }
 
-func (n *Node) Render(url string, isKitty bool) string {
-	var sb strings.Builder
-	state := &renderState{builder: &sb}
-	n.renderRecursive(state, url, isKitty)
-	return sb.String()
+// maxImageWidth is the widest an inline image is ever shown, in pixels.
+func (s *renderState) maxImageWidth() int {
+	return min(config.GetSixelConfig().MaxWidth, s.viewportWidth())
 }
 
-func (n *Node) renderRecursive(state *renderState, url string, isKitty bool) {
-	isBlock := isBlockElement(n.Element.NodeType)
+// inlineImage creates the image for an IMG element, or returns nil when
+// images are disabled or it can't be loaded.
+func (s *renderState) inlineImage(url, alt string, isKitty bool) *InlineImage {
+	if !config.GetSixelConfig().Enabled || url == "" {
+		return nil
+	}
+
+	anim, err := helper.AnimationFromURL(url)
+	if err != nil {
+		return nil
+	}
+
+	img := newInlineImage(url, alt, anim, s.width, isKitty)
+	s.page.Images = append(s.page.Images, img)
+	return img
+}
 
-	if isBlock {
-		state.ensureNewline()
+// inlineSVG rasterises an inline <svg> element, or returns nil when images
+// are disabled or the markup can't be drawn.
+func (s *renderState) inlineSVG(markup, alt string, isKitty bool) *InlineImage {
+	if !config.GetSixelConfig().Enabled || markup == "" {
+		return nil
 	}
 
-	isList := (n.Element.NodeType == UL || n.Element.NodeType == OL)
-	if isList {
-		state.listIndex = 0
+	raster, err := svg.Decode(strings.NewReader(markup))
+	if err != nil {
+		return nil
 	}
 
-	var content string
-	if len(n.Children) > 0 {
-		var childrenBuilder strings.Builder
-		childrenState := &renderState{builder: &childrenBuilder, listIndex: state.listIndex}
-		for i, child := range n.Children {
-			child.renderRecursive(childrenState, url, isKitty)
+	anim := &helper.Animation{Frames: []image.Image{raster}, Data: []byte(markup), Format: "svg"}
+	img := newInlineImage("", alt, anim, s.width, isKitty)
+	s.page.Images = append(s.page.Images, img)
+	return img
+}
 
-			if i < len(n.Children)-1 && !isBlockElement(child.Element.NodeType) && !isBlockElement(n.Children[i+1].Element.NodeType) {
-				childrenBuilder.WriteString(" ")
-			}
-		}
-		state.listIndex = childrenState.listIndex
-		content = childrenBuilder.String()
-	} else {
-		content = n.InnerText
+func (n *Node) Render(url string, width int, isKitty bool) Page {
+	page := Page{}
+	state := &renderState{width: width, lineWidth: width, page: &page}
+
+	if config.GetSixelConfig().Enabled {
+		prefetchImages(n, url, state.maxImageWidth(), state.viewportWidth())
+	}
+
+	page.Content = n.layoutBlock(state, url, isKitty).text + "\n"
+	if config.GetLinkDisplay() == config.LINK_DISPLAY_FOOTNOTE {
+		page.Content += state.references()
 	}
+	return page
+}
 
-	var finalOutput string
+// decorate shows the element n around its laid out content. It returns the
+// vertical margins of the element apart, for the layout to collapse them
+// with the margins next to it.
+func (n *Node) decorate(state *renderState, content string, url string, isKitty bool) (finalOutput string, top, bottom int) {
 	switch n.Element.NodeType {
 	case LI:
-		if state.listIndex > 0 {
-			finalOutput = fmt.Sprintf("  %d. %s", state.listIndex, content)
-		} else {
-			finalOutput = "  • " + content
-		}
-	case OL:
-		state.listIndex = 1
-		finalOutput = content
-	case UL:
-		state.listIndex = 0
+		finalOutput = state.listItem(n, content)
+	case UL, OL:
 		finalOutput = content
 
 	case A:
 		href, ok := n.Element.Attrs["href"]
-		if ok {
-			finalOutput = fmt.Sprintf("%s %s", content, config.AddStyle("a", href))
-		} else {
+		switch {
+		case !ok:
 			finalOutput = content
+		case config.GetLinkDisplay() == config.LINK_DISPLAY_FOOTNOTE:
+			reference := fmt.Sprintf("[%d]", state.linkReference(url, href))
+			finalOutput = content + config.RenderStyle("a", reference)
+		default:
+			finalOutput = fmt.Sprintf("%s %s", content, config.AddStyle("a", href))
 		}
 	case IMG:
 		alt := n.Element.Attrs["alt"]
-		imgUrl := n.Element.Attrs["src"]
+		imgUrl := imageSource(n.Element.Attrs, state.maxImageWidth(), state.viewportWidth())
 		if validUrl, err := helper.ResolveURL(url, imgUrl); err == nil {
 			imgUrl = validUrl
 		}
 
-		// Get sixel config and encode image
-		// cfg := config.GetSixelConfig()
-		// if cfg.Enabled && imgUrl != "" {
-		// 	if data, err := sixel.EncodeFromURL(imgUrl, cfg.MaxWidth, cfg.MaxHeight); err == nil && data != "" {
-		// 		finalOutput = "\n" + data + "\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n"
-		// 	}
-		// } else {
-		// 	finalOutput = ItalicStyle.Render(fmt.Sprintf("[Image: %s]", alt))
-		// }
-		finalOutput = ItalicStyle.Render(fmt.Sprintf("[Image: %s, Url: %s]", alt, imgUrl))
+		if img := state.inlineImage(imgUrl, alt, isKitty); img != nil {
+			finalOutput = "\n" + img.placeholder() + "\n"
+		} else {
+			finalOutput = ItalicStyle.Render(fmt.Sprintf("[Image: %s, Url: %s]", alt, imgUrl))
+		}
+
+	case SVG:
+		alt := n.Element.Attrs["alt"]
+		if img := state.inlineSVG(n.InnerText, alt, isKitty); img != nil {
+			finalOutput = "\n" + img.placeholder() + "\n"
+		} else {
+			finalOutput = ItalicStyle.Render(fmt.Sprintf("[SVG: %s]", alt))
+		}
+
+	case DT:
+		finalOutput = styled(n.Element.Name, TermStyle, content)
+	case DD:
+		indent := 2 * config.GetListConfig().Indent
+		finalOutput = strings.Repeat(" ", indent) + hangingIndent(config.AddStyle(n.Element.Name, content), indent)
+	case SUMMARY:
+		finalOutput = styled(n.Element.Name, SummaryStyle, content)
+	case FIGCAPTION:
+		finalOutput = styled(n.Element.Name, CaptionStyle, content)
+	case MARK:
+		finalOutput = styled(n.Element.Name, MarkStyle, content)
+	case KBD:
+		finalOutput = styled(n.Element.Name, KbdStyle, content)
+	case DEL:
+		finalOutput = styled(n.Element.Name, DelStyle, content)
+	case INS:
+		finalOutput = styled(n.Element.Name, InsStyle, content)
+	case ABBR:
+		finalOutput = styled(n.Element.Name, AbbrStyle, content)
+		if title := strings.TrimSpace(n.Element.Attrs["title"]); title != "" {
+			finalOutput += HrStyle.Render(" (" + title + ")")
+		}
+	case SUB:
+		finalOutput = config.AddStyle(n.Element.Name, scriptText(content, subscripts, "_"))
+	case SUP:
+		finalOutput = config.AddStyle(n.Element.Name, scriptText(content, superscripts, "^"))
 
 	case BLOCKQUOTE:
 		finalOutput = BlockquoteStyle.Render(content)
 	case PRE:
-		finalOutput = lipgloss.NewStyle().Margin(1, 2).Render(content)
+		if code, ok := n.highlightCode(); ok {
+			content = code
+		}
+		// the line break before </pre> doesn't start another line
+		content = expandTabs(strings.TrimSuffix(content, "\n"))
+		if state.preformatted {
+			finalOutput = content
+		} else {
+			finalOutput = state.noWrap(lipgloss.NewStyle().Margin(0, 2).Render(content))
+			top, bottom = 1, 1
+		}
 	case HR:
 		finalOutput = HrStyle.Render(strings.Repeat("─", 50))
 	case BR:
-		finalOutput = HrStyle.Render(" ")
-	case STYLE, SCRIPT, IFRAME:
+		finalOutput = "\n"
+	case STYLE, SCRIPT, IFRAME, SOURCE:
 
 	case INPUT:
 		// TODO: Make this input system better
 		finalOutput = InputBackgroundStyle.Render("█") + InputStyle.Render(n.Element.Attrs["placeholder"]) + InputBackgroundStyle.Render(strings.Repeat("█", 10))
-	default:
-		finalOutput = config.AddStyle(n.Element.Name, content)
-	}
-
-	state.builder.WriteString(finalOutput)
-
-	if isBlock {
-
-		if n.Element.NodeType != LI {
-			state.builder.WriteRune('\n')
-		}
-
-		if n.Element.NodeType == LI && state.listIndex > 0 {
-			state.listIndex++
+	case TEXTAREA:
+		text := strings.TrimSuffix(content, "\n")
+		if text == "" {
+			text = n.Element.Attrs["placeholder"]
 		}
+		finalOutput = state.noWrap(InputStyle.Padding(0, 1).Render(expandTabs(text)))
+	default:
+		finalOutput, top, bottom = config.AddBlockStyle(n.Element.Name, content)
 	}
+	return finalOutput, top, bottom
 }
 
 func stripANSICodes(s string) string {
@@ -256,10 +390,7 @@ func normalizeNewlines(s string) string {
 		// 	continue
 		// }
 
-		cleanLine := strings.TrimSpace(stripANSICodes(line))
-		isEmptyLine := cleanLine == ""
-
-		if isEmptyLine {
+		if !isVisible(line) {
 			consecutiveEmptyLines++
 			if consecutiveEmptyLines <= maxGaps {
 				result = append(result, "")
@@ -319,23 +450,41 @@ func restoreSixels(text string, sixels []string) string {
 	return result
 }
 
+// WordWrap wraps text to maxWidth, except for its preformatted blocks.
 func WordWrap(text string, maxWidth int) string {
 	if maxWidth <= 0 {
-		return text
+		return stripNoWrapMarkers(text)
+	}
+	if !strings.Contains(text, noWrapStart) {
+		return wrapText(text, maxWidth)
 	}
 
+	var sb strings.Builder
+	for _, region := range splitNoWrap(text) {
+		if region.noWrap {
+			sb.WriteString(trimLines(region.text))
+		} else {
+			sb.WriteString(wrapText(region.text, maxWidth))
+		}
+	}
+	return sb.String()
+}
+
+func wrapText(text string, maxWidth int) string {
 	// Extract sixel sequences before word wrapping to preserve them
 	textWithoutSixels, sixels := extractSixels(text)
 
 	wordWrapper := wordwrap.NewWriter(maxWidth)
 	wordWrapper.Breakpoints = []rune{' ', '\t', '-', '–', '—', ':', ',', ';', '.', '!', '?', '/', '\\'}
 	wordWrapper.Write([]byte(textWithoutSixels))
+	// closing flushes the last word, which has no space after it
+	wordWrapper.Close()
 	wrappedText := wordWrapper.String()
 
 	unconditionalWrapper := wrap.NewWriter(maxWidth)
 	unconditionalWrapper.KeepNewlines = true
 	unconditionalWrapper.PreserveSpace = false
-	unconditionalWrapper.TabWidth = 4
+	unconditionalWrapper.TabWidth = tabWidth
 	unconditionalWrapper.Write([]byte(wrappedText))
 	finalText := unconditionalWrapper.String()
 
@@ -353,7 +502,8 @@ func WordWrap(text string, maxWidth int) string {
 
 func isBlockElement(nodeType uint) bool {
 	switch nodeType {
-	case H1, H2, H3, H4, H5, H6, P, DIV, UL, OL, LI, PRE, BLOCKQUOTE, HR, ROOT, BR:
+	case H1, H2, H3, H4, H5, H6, P, DIV, UL, OL, LI, PRE, BLOCKQUOTE, HR, ROOT, TEXTAREA,
+		DL, DT, DD, DETAILS, SUMMARY, FIGURE, FIGCAPTION:
 		return true
 	default:
 		return false
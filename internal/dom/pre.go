@@ -0,0 +1,116 @@
+package dom
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// tabWidth is the distance between tab stops in preformatted text, and the
+// width of a tab anywhere else.
+const tabWidth = 4
+
+// noWrapStart and noWrapEnd enclose preformatted blocks, which WordWrap
+// leaves as they are: their long lines are scrolled sideways instead. Like
+// the anchor marker, they look like escape sequences with no width.
+const (
+	noWrapStart = "\x1b[7778z"
+	noWrapEnd   = "\x1b[7779z"
+)
+
+// noWrap keeps text out of word wrapping, unless it is already inside a
+// preformatted block.
+func (s *renderState) noWrap(text string) string {
+	if s.preformatted {
+		return text
+	}
+	return noWrapStart + text + noWrapEnd
+}
+
+// expandTabs replaces the tabs in text with spaces up to the next tab stop,
+// counting columns from the start of each line and skipping escape
+// sequences.
+func expandTabs(text string) string {
+	if !strings.Contains(text, "\t") {
+		return text
+	}
+
+	var sb strings.Builder
+	column := 0
+	inEscape := false
+	for _, r := range text {
+		switch {
+		case r == '\x1b':
+			inEscape = true
+		case inEscape:
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+				inEscape = false
+			}
+		case r == '\n':
+			column = 0
+		case r == '\t':
+			spaces := tabWidth - column%tabWidth
+			sb.WriteString(strings.Repeat(" ", spaces))
+			column += spaces
+			continue
+		default:
+			column += runewidth.RuneWidth(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// textRegion is a part of the page, either wrapped or left as it is.
+type textRegion struct {
+	text   string
+	noWrap bool
+}
+
+// splitNoWrap splits text into regions around its preformatted blocks. What
+// comes before a block on its first line, such as indentation or an anchor
+// marker, goes with the block.
+func splitNoWrap(text string) []textRegion {
+	var regions []textRegion
+	for text != "" {
+		start := strings.Index(text, noWrapStart)
+		if start < 0 {
+			regions = append(regions, textRegion{text: text})
+			break
+		}
+
+		lineStart := strings.LastIndex(text[:start], "\n") + 1
+		if lineStart > 0 {
+			regions = append(regions, textRegion{text: text[:lineStart]})
+		}
+
+		block := text[lineStart:start]
+		text = text[start+len(noWrapStart):]
+		end := strings.Index(text, noWrapEnd)
+		if end < 0 {
+			end = len(text)
+		}
+		block += text[:end]
+		text = strings.TrimPrefix(text[end:], noWrapEnd)
+
+		regions = append(regions, textRegion{text: block, noWrap: true})
+	}
+	return regions
+}
+
+// trimLines removes the white space at the end of every line of text.
+func trimLines(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// stripNoWrapMarkers removes the markers of preformatted blocks from text.
+func stripNoWrapMarkers(text string) string {
+	if !strings.Contains(text, noWrapStart) {
+		return text
+	}
+	return strings.NewReplacer(noWrapStart, "", noWrapEnd, "").Replace(text)
+}
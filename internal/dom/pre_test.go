@@ -0,0 +1,88 @@
+package dom
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandTabs(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{"no tabs", "a b", "a b"},
+		{"to the next stop", "a\tb", "a   b"},
+		{"at a stop", "abcd\te", "abcd    e"},
+		{"several", "\t\tx", "        x"},
+		{"each line from its start", "ab\tc\n\td", "ab  c\n    d"},
+		{"escape codes have no width", "\x1b[1mab\x1b[0m\tc", "\x1b[1mab\x1b[0m  c"},
+		{"wide characters", "日本\tx", "日本    x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandTabs(tt.text); got != tt.want {
+				t.Errorf("expandTabs(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitNoWrap(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []textRegion
+	}{
+		{
+			name: "no block",
+			text: "just text",
+			want: []textRegion{{text: "just text"}},
+		},
+		{
+			name: "block between text",
+			text: "before\n" + noWrapStart + "code" + noWrapEnd + "\nafter",
+			want: []textRegion{{text: "before\n"}, {text: "code", noWrap: true}, {text: "\nafter"}},
+		},
+		{
+			name: "indentation goes with the block",
+			text: "before\n  " + noWrapStart + "code" + noWrapEnd,
+			want: []textRegion{{text: "before\n"}, {text: "  code", noWrap: true}},
+		},
+		{
+			name: "unclosed block",
+			text: noWrapStart + "code",
+			want: []textRegion{{text: "code", noWrap: true}},
+		},
+		{
+			name: "two blocks",
+			text: noWrapStart + "a" + noWrapEnd + "\n" + noWrapStart + "b" + noWrapEnd,
+			want: []textRegion{{text: "a", noWrap: true}, {text: "\n"}, {text: "b", noWrap: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitNoWrap(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitNoWrap(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWordWrapPreformatted(t *testing.T) {
+	prose := "the quick brown fox jumps over the lazy dog and keeps running"
+	code := "for i := 0; i < len(items); i++ { total += items[i] }   "
+
+	// text outside of the blocks wraps as if they weren't there
+	want := wrapText(prose, 20) + "\n" + strings.TrimRight(code, " ") + "\n" + wrapText(prose, 20)
+	got := WordWrap(prose+"\n"+noWrapStart+code+noWrapEnd+"\n"+prose, 20)
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got, want := WordWrap(prose, 20), wrapText(prose, 20); got != want {
+		t.Errorf("without blocks got %q, want %q", got, want)
+	}
+	if got, want := WordWrap(noWrapStart+code+noWrapEnd, 0), code; got != want {
+		t.Errorf("without a width got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,271 @@
+package dom
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// minParagraphLength is the shortest text that counts towards the
+	// score of the elements around it.
+	minParagraphLength = 25
+	// minArticleLength is the least text a page needs for reader mode to
+	// find an article in it.
+	minArticleLength = 250
+)
+
+var (
+	// unlikelyNames and likelyNames are matched against class and id to
+	// drop page chrome before scoring, unless it looks like content too.
+	unlikelyNames = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|menu|modal|nav|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe`)
+	likelyNames   = regexp.MustCompile(`(?i)and|article|body|column|content|main|post|entry|story|text`)
+
+	// positiveNames and negativeNames weigh the score of an element by its
+	// class and id.
+	positiveNames = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeNames = regexp.MustCompile(`(?i)hidden|banner|combx|comment|com-|contact|foot|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+
+	// chromeTags never hold the article. A header only counts as chrome
+	// outside of an article, where it holds the site's navigation.
+	chromeTags = map[string]bool{
+		"nav": true, "aside": true, "footer": true, "form": true, "button": true,
+		"noscript": true, "menu": true, "dialog": true, "select": true,
+	}
+)
+
+// readerScores keeps the content score of the elements of a page and the
+// parent of every element, to look at the siblings of the best one.
+type readerScores struct {
+	score  map[*Node]float64
+	parent map[*Node]*Node
+}
+
+// Readable returns the main content of a page, the way reader modes do: the
+// page is stripped of navigation and other chrome, every element is scored by
+// the paragraphs in it, their length and how few links they hold, and the
+// best element is kept together with siblings that look like part of the
+// same article. title heads the result unless the article has its own h1.
+// It reports false when nothing on the page looks like an article.
+func Readable(doc Node, title string) (Node, bool) {
+	cleaned := pruneChrome(doc, false)
+
+	scores := &readerScores{score: map[*Node]float64{}, parent: map[*Node]*Node{}}
+	scores.walk(&cleaned, nil)
+
+	var best *Node
+	bestScore := 0.0
+	for n, score := range scores.score {
+		score *= 1 - linkDensity(n)
+		scores.score[n] = score
+		if score > bestScore || score == bestScore && best != nil && textLength(n) > textLength(best) {
+			best, bestScore = n, score
+		}
+	}
+	if best == nil {
+		return doc, false
+	}
+
+	article := Node{Element: ElementData{NodeType: DIV, Name: "article", Attrs: map[string]string{}}}
+	if parent := scores.parent[best]; parent != nil {
+		threshold := max(10, bestScore*0.2)
+		for i := range parent.Children {
+			sibling := &parent.Children[i]
+			if sibling == best || scores.score[sibling] >= threshold || isArticleParagraph(sibling) {
+				article.Children = append(article.Children, dropLinkLists(*sibling))
+			}
+		}
+	} else {
+		article.Children = append(article.Children, dropLinkLists(*best))
+	}
+
+	if textLength(&article) < minArticleLength {
+		return doc, false
+	}
+
+	if title = strings.TrimSpace(title); title != "" && !hasHeading(&article, H1) {
+		heading := Node{
+			Element:  ElementData{NodeType: H1, Name: "h1", Attrs: map[string]string{}},
+			Children: []Node{{Element: ElementData{NodeType: TEXT}, InnerText: title}},
+		}
+		article.Children = append([]Node{heading}, article.Children...)
+	}
+	return article, true
+}
+
+// pruneChrome returns a copy of n without scripts, forms, navigation and
+// other elements that are never part of an article.
+func pruneChrome(n Node, inArticle bool) Node {
+	name := n.Element.Name
+	inArticle = inArticle || name == "article" || name == "main"
+
+	children := make([]Node, 0, len(n.Children))
+	for _, child := range n.Children {
+		if isChrome(&child, inArticle) {
+			continue
+		}
+		children = append(children, pruneChrome(child, inArticle))
+	}
+	n.Children = children
+	return n
+}
+
+func isChrome(n *Node, inArticle bool) bool {
+	switch n.Element.NodeType {
+	case STYLE, SCRIPT, IFRAME, INPUT, TEXTAREA:
+		return true
+	case TEXT:
+		return false
+	}
+
+	name := n.Element.Name
+	if chromeTags[name] || name == "header" && !inArticle {
+		return true
+	}
+	if name == "body" || name == "html" || name == "article" || name == "main" {
+		return false
+	}
+
+	names := n.Element.Attrs["class"] + " " + n.Element.Attrs["id"]
+	return unlikelyNames.MatchString(names) && !likelyNames.MatchString(names)
+}
+
+// walk scores the paragraphs below n: each adds to its parent, half as much
+// to its grandparent and a third to the element above that.
+func (s *readerScores) walk(n *Node, ancestors []*Node) {
+	if len(ancestors) > 0 {
+		s.parent[n] = ancestors[len(ancestors)-1]
+	}
+
+	if isParagraph(n) {
+		if length := textLength(n); length >= minParagraphLength {
+			score := 1 + float64(strings.Count(n.textContent(), ",")) + min(float64(length)/100, 3)
+			for level := 1; level <= 3 && level <= len(ancestors); level++ {
+				ancestor := ancestors[len(ancestors)-level]
+				if _, ok := s.score[ancestor]; !ok {
+					s.score[ancestor] = baseScore(ancestor)
+				}
+				s.score[ancestor] += score / float64(level)
+			}
+		}
+	}
+
+	ancestors = append(ancestors, n)
+	for i := range n.Children {
+		s.walk(&n.Children[i], ancestors)
+	}
+}
+
+// isParagraph reports whether n is a block of text: a paragraph, or a div
+// without blocks of its own.
+func isParagraph(n *Node) bool {
+	switch n.Element.NodeType {
+	case P, PRE, BLOCKQUOTE:
+		return true
+	case DIV:
+		for _, child := range n.Children {
+			if isBlockElement(child.Element.NodeType) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// baseScore is what an element scores before its paragraphs are counted.
+func baseScore(n *Node) float64 {
+	score := 0.0
+	switch n.Element.Name {
+	case "article", "main":
+		score += 15
+	case "div", "section":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "ol", "ul", "li", "dl", "dd", "dt":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+
+	for _, attr := range []string{n.Element.Attrs["class"], n.Element.Attrs["id"]} {
+		if attr == "" {
+			continue
+		}
+		if negativeNames.MatchString(attr) {
+			score -= 25
+		}
+		if positiveNames.MatchString(attr) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// isArticleParagraph reports whether a sibling of the best element is a
+// paragraph of the article that didn't score on its own.
+func isArticleParagraph(n *Node) bool {
+	if n.Element.NodeType != P {
+		return false
+	}
+	length := textLength(n)
+	density := linkDensity(n)
+	return length > 80 && density < 0.25 ||
+		length > 0 && density == 0 && strings.HasSuffix(strings.TrimSpace(n.textContent()), ".")
+}
+
+// dropLinkLists returns a copy of n without the lists and blocks below it
+// that are mostly links, like "related articles" boxes.
+func dropLinkLists(n Node) Node {
+	children := make([]Node, 0, len(n.Children))
+	for _, child := range n.Children {
+		switch child.Element.NodeType {
+		case UL, OL, DIV:
+			if linkDensity(&child) > 0.5 && textLength(&child) < 1000 {
+				continue
+			}
+		}
+		children = append(children, dropLinkLists(child))
+	}
+	n.Children = children
+	return n
+}
+
+// hasHeading reports whether n holds a heading of the given type.
+func hasHeading(n *Node, nodeType uint) bool {
+	if n.Element.NodeType == nodeType {
+		return true
+	}
+	for i := range n.Children {
+		if hasHeading(&n.Children[i], nodeType) {
+			return true
+		}
+	}
+	return false
+}
+
+// textLength is the length of the text below n, with runs of white space
+// counted once.
+func textLength(n *Node) int {
+	return len(strings.Join(strings.Fields(n.textContent()), " "))
+}
+
+// linkDensity is the share of the text below n that is link text.
+func linkDensity(n *Node) float64 {
+	total := textLength(n)
+	if total == 0 {
+		return 0
+	}
+	return float64(linkTextLength(n)) / float64(total)
+}
+
+func linkTextLength(n *Node) int {
+	if n.Element.NodeType == A {
+		return textLength(n)
+	}
+	length := 0
+	for i := range n.Children {
+		length += linkTextLength(&n.Children[i])
+	}
+	return length
+}
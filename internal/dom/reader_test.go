@@ -0,0 +1,137 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+)
+
+// block is an element the parser reads as a plain block, like nav or
+// article.
+func block(name string, children ...Node) Node {
+	return Node{Element: ElementData{NodeType: DIV, Name: name, Attrs: map[string]string{}}, Children: children}
+}
+
+func paragraph(s string) Node {
+	return element("p", text(s))
+}
+
+func link(s string) Node {
+	return element("a", text(s))
+}
+
+var articleParagraphs = []Node{
+	paragraph("Reader modes keep the text of a page and drop the menus, ads and footers around it, so the article reads like a book."),
+	paragraph("They score every element by the paragraphs in it, their length, their commas and how few of their words are links."),
+	paragraph("The best element wins, together with siblings that look like part of the same article, such as a lone closing paragraph."),
+}
+
+func TestReadable(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     Node
+		title   string
+		ok      bool
+		want    []string
+		notWant []string
+		h1s     int
+	}{
+		{
+			name: "article among chrome",
+			doc: block("body",
+				block("nav", element("ul", element("li", link("Home")), element("li", link("Archive")))),
+				block("header", paragraph("The site header with its tagline, which is long enough to score.")),
+				block("article", articleParagraphs...),
+				block("aside", paragraph("An aside about something else entirely, long enough to score, too.")),
+				block("footer", paragraph("Copyright and contact details, which are long enough to score.")),
+			),
+			title:   "Reader mode",
+			ok:      true,
+			want:    []string{"Reader mode", "book", "commas", "closing paragraph"},
+			notWant: []string{"Home", "tagline", "aside", "Copyright"},
+			h1s:     1,
+		},
+		{
+			name: "chrome by class name",
+			doc: block("body",
+				withAttr(element("div", articleParagraphs...), "class", "post"),
+				withAttr(element("div", paragraph("Share this with your friends, followers and everyone you know.")), "class", "social"),
+			),
+			ok:      true,
+			want:    []string{"book", "commas"},
+			notWant: []string{"friends"},
+		},
+		{
+			name: "list of links dropped",
+			doc: block("body", block("article", append(articleParagraphs[:3:3],
+				element("ul", element("li", link("Another story")), element("li", link("One more story"))))...)),
+			ok:      true,
+			want:    []string{"book"},
+			notWant: []string{"story"},
+		},
+		{
+			name:    "own h1 kept",
+			doc:     block("body", block("article", append([]Node{element("h1", text("The article's own title"))}, articleParagraphs...)...)),
+			title:   "Site name | The article's own title",
+			ok:      true,
+			want:    []string{"The article's own title", "book"},
+			notWant: []string{"Site name"},
+			h1s:     1,
+		},
+		{
+			name: "no article",
+			doc: block("body",
+				block("nav", element("ul", element("li", link("Home")), element("li", link("Search")))),
+				paragraph("Nothing much to read here, just a short line."),
+			),
+			title: "Home",
+			ok:    false,
+		},
+		{
+			name:  "mostly links",
+			doc:   block("body", element("div", element("p", link("A paragraph that is nothing but a link to another page, made long")))),
+			title: "Links",
+			ok:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Readable(tt.doc, tt.title)
+			if ok != tt.ok {
+				t.Fatalf("Readable reported %v, want %v", ok, tt.ok)
+			}
+
+			content := got.textContent()
+			if !ok {
+				if content != tt.doc.textContent() {
+					t.Errorf("Readable changed a page without an article: %q", content)
+				}
+				return
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(content, s) {
+					t.Errorf("article is missing %q: %q", s, content)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(content, s) {
+					t.Errorf("article holds %q: %q", s, content)
+				}
+			}
+			if h1s := countType(&got, H1); h1s != tt.h1s {
+				t.Errorf("article has %d h1, want %d", h1s, tt.h1s)
+			}
+		})
+	}
+}
+
+func countType(n *Node, nodeType uint) int {
+	count := 0
+	if n.Element.NodeType == nodeType {
+		count++
+	}
+	for i := range n.Children {
+		count += countType(&n.Children[i], nodeType)
+	}
+	return count
+}
@@ -0,0 +1,282 @@
+package dom
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// srcCandidate is one entry of a srcset attribute. Only one of Width (a "w"
+// descriptor) or Density (an "x" descriptor) is set.
+type srcCandidate struct {
+	URL     string
+	Width   int
+	Density float64
+}
+
+var (
+	// lazySrcAttrs and lazySrcsetAttrs are the attributes lazy-loading
+	// scripts commonly move the real image into.
+	lazySrcAttrs    = []string{"data-src", "data-lazy-src", "data-original", "data-url"}
+	lazySrcsetAttrs = []string{"data-srcset", "data-lazy-srcset"}
+
+	// supportedImageTypes are the MIME types of <source type> we can decode.
+	supportedImageTypes = map[string]bool{
+		"image/png":     true,
+		"image/jpeg":    true,
+		"image/jpg":     true,
+		"image/gif":     true,
+		"image/webp":    true,
+		"image/svg+xml": true,
+	}
+
+	mediaFeaturePattern = regexp.MustCompile(`\(\s*(min|max)-width\s*:\s*([0-9.]+)(px|em|rem)?\s*\)`)
+)
+
+// parseSrcset splits a srcset attribute into its candidates.
+func parseSrcset(srcset string) []srcCandidate {
+	var candidates []srcCandidate
+	for _, entry := range splitSrcset(srcset) {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+
+		candidate := srcCandidate{URL: fields[0], Density: 1}
+		if len(fields) > 1 {
+			descriptor := strings.ToLower(fields[1])
+			switch {
+			case strings.HasSuffix(descriptor, "w"):
+				if w, err := strconv.Atoi(strings.TrimSuffix(descriptor, "w")); err == nil {
+					candidate.Width = w
+					candidate.Density = 0
+				}
+			case strings.HasSuffix(descriptor, "x"):
+				if d, err := strconv.ParseFloat(strings.TrimSuffix(descriptor, "x"), 64); err == nil {
+					candidate.Density = d
+				}
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+// splitSrcset splits on the commas separating candidates, leaving commas
+// inside URLs (common in CDN resize parameters) alone.
+func splitSrcset(srcset string) []string {
+	var entries []string
+	start := 0
+	inURL := true
+	for i, r := range srcset {
+		switch {
+		case r < 0x80 && isSrcsetSpace(byte(r)):
+			if strings.TrimSpace(srcset[start:i]) != "" {
+				inURL = false
+			}
+		case r == ',' && (!inURL || i+1 == len(srcset) || isSrcsetSpace(srcset[i+1])):
+			entries = append(entries, srcset[start:i])
+			start = i + 1
+			inURL = true
+		}
+	}
+	return append(entries, srcset[start:])
+}
+
+func isSrcsetSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+// pickCandidate returns the URL of the candidate best suited to show an image
+// slotWidth pixels wide: the smallest one at least that wide, or the largest
+// available if none is.
+func pickCandidate(candidates []srcCandidate, slotWidth int) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sorted := append([]srcCandidate(nil), candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return effectiveWidth(sorted[i], slotWidth) < effectiveWidth(sorted[j], slotWidth)
+	})
+
+	for _, c := range sorted {
+		if effectiveWidth(c, slotWidth) >= float64(slotWidth) {
+			return c.URL
+		}
+	}
+	return sorted[len(sorted)-1].URL
+}
+
+// effectiveWidth is the pixel width a candidate covers. Density candidates
+// are relative to the slot, so 1x always fits.
+func effectiveWidth(c srcCandidate, slotWidth int) float64 {
+	if c.Width > 0 {
+		return float64(c.Width)
+	}
+	return c.Density * float64(slotWidth)
+}
+
+// slotWidth evaluates a sizes attribute against a viewport viewportWidth
+// pixels wide, returning the pixel width the image will be shown at.
+func slotWidth(sizes string, viewportWidth int) int {
+	for _, entry := range strings.Split(sizes, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		media, length := "", entry
+		if idx := strings.LastIndex(entry, ")"); idx != -1 {
+			media, length = entry[:idx+1], strings.TrimSpace(entry[idx+1:])
+		}
+		if media != "" && !matchesMedia(media, viewportWidth) {
+			continue
+		}
+		if w, ok := parseLength(length, viewportWidth); ok {
+			return w
+		}
+	}
+	return viewportWidth
+}
+
+// matchesMedia evaluates the min-width/max-width features of a media
+// condition. Anything else is assumed to match.
+func matchesMedia(media string, viewportWidth int) bool {
+	for _, match := range mediaFeaturePattern.FindAllStringSubmatch(media, -1) {
+		v, err := strconv.ParseFloat(match[2], 64)
+		if err != nil {
+			continue
+		}
+		if match[3] == "em" || match[3] == "rem" {
+			v *= 16
+		}
+		if match[1] == "min" && float64(viewportWidth) < v {
+			return false
+		}
+		if match[1] == "max" && float64(viewportWidth) > v {
+			return false
+		}
+	}
+	return true
+}
+
+// parseLength converts a sizes length (px, vw or em) to pixels.
+func parseLength(length string, viewportWidth int) (int, bool) {
+	length = strings.TrimSpace(strings.ToLower(length))
+	for _, unit := range []struct {
+		suffix string
+		scale  float64
+	}{
+		{"px", 1},
+		{"vw", float64(viewportWidth) / 100},
+		{"rem", 16},
+		{"em", 16},
+	} {
+		if strings.HasSuffix(length, unit.suffix) {
+			v, err := strconv.ParseFloat(strings.TrimSuffix(length, unit.suffix), 64)
+			if err != nil {
+				return 0, false
+			}
+			return int(v * unit.scale), true
+		}
+	}
+	return 0, false
+}
+
+// isPlaceholderSrc reports whether src is one of the tiny inline images
+// lazy-loading scripts put in place of the real one.
+func isPlaceholderSrc(src string) bool {
+	return src == "" || strings.HasPrefix(src, "data:") || strings.Contains(src, "placeholder") ||
+		strings.Contains(src, "blank.gif") || strings.Contains(src, "spacer.gif")
+}
+
+// srcsetAttr returns the srcset of an element, including the ones hidden in
+// lazy-loading attributes.
+func srcsetAttr(attrs map[string]string) string {
+	if srcset := attrs["srcset"]; srcset != "" {
+		return srcset
+	}
+	for _, attr := range lazySrcsetAttrs {
+		if srcset := attrs[attr]; srcset != "" {
+			return srcset
+		}
+	}
+	return ""
+}
+
+// imageSource picks the URL to load for an IMG element shown in a slot at
+// most maxWidth pixels wide, on a viewport viewportWidth pixels wide.
+func imageSource(attrs map[string]string, maxWidth, viewportWidth int) string {
+	if srcset := srcsetAttr(attrs); srcset != "" {
+		slot := viewportWidth
+		if sizes, ok := attrs["sizes"]; ok && sizes != "auto" {
+			slot = slotWidth(sizes, viewportWidth)
+		}
+		if url := pickCandidate(parseSrcset(srcset), min(slot, maxWidth)); url != "" {
+			return url
+		}
+	}
+
+	src := attrs["src"]
+	if isPlaceholderSrc(src) {
+		for _, attr := range lazySrcAttrs {
+			if lazy := attrs[attr]; lazy != "" {
+				return lazy
+			}
+		}
+	}
+	return src
+}
+
+// resolvePicture returns the <img> of a <picture> element with the srcset of
+// the first <source> we can use. Sources with an unsupported type or a
+// media condition that doesn't match are skipped, as browsers do.
+func (n *Node) resolvePicture(viewportWidth int) (Node, bool) {
+	var img *Node
+	for i := range n.Children {
+		if n.Children[i].Element.NodeType == IMG {
+			img = &n.Children[i]
+			break
+		}
+	}
+	if img == nil {
+		return Node{}, false
+	}
+
+	resolved := *img
+	resolved.Element.Attrs = make(map[string]string, len(img.Element.Attrs))
+	for k, v := range img.Element.Attrs {
+		resolved.Element.Attrs[k] = v
+	}
+
+	for _, child := range n.Children {
+		if child.Element.NodeType != SOURCE {
+			continue
+		}
+
+		attrs := child.Element.Attrs
+		if t, ok := attrs["type"]; ok && !supportedImageTypes[strings.ToLower(strings.TrimSpace(t))] {
+			continue
+		}
+		if media, ok := attrs["media"]; ok && !matchesMedia(media, viewportWidth) {
+			continue
+		}
+
+		srcset := srcsetAttr(attrs)
+		if srcset == "" {
+			continue
+		}
+
+		resolved.Element.Attrs["srcset"] = srcset
+		if sizes, ok := attrs["sizes"]; ok {
+			resolved.Element.Attrs["sizes"] = sizes
+		} else {
+			delete(resolved.Element.Attrs, "sizes")
+		}
+		break
+	}
+
+	return resolved, true
+}
@@ -0,0 +1,108 @@
+package dom
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSrcset(t *testing.T) {
+	tests := []struct {
+		name   string
+		srcset string
+		want   []srcCandidate
+	}{
+		{
+			name:   "width descriptors",
+			srcset: "small.jpg 320w, large.jpg 1280w",
+			want:   []srcCandidate{{URL: "small.jpg", Width: 320}, {URL: "large.jpg", Width: 1280}},
+		},
+		{
+			name:   "density descriptors",
+			srcset: "a.jpg, b.jpg 1.5x,c.jpg 2X",
+			want:   []srcCandidate{{URL: "a.jpg", Density: 1}, {URL: "b.jpg", Density: 1.5}, {URL: "c.jpg", Density: 2}},
+		},
+		{
+			name:   "commas inside URLs",
+			srcset: "img.jpg?w=100,h=50 100w, img.jpg?w=200,h=100 200w",
+			want:   []srcCandidate{{URL: "img.jpg?w=100,h=50", Width: 100}, {URL: "img.jpg?w=200,h=100", Width: 200}},
+		},
+		{
+			name:   "empty entries",
+			srcset: " , a.jpg 10w,, ",
+			want:   []srcCandidate{{URL: "a.jpg", Width: 10}},
+		},
+		{
+			name:   "malformed descriptors fall back to 1x",
+			srcset: "a.jpg widew, b.jpg 2q",
+			want:   []srcCandidate{{URL: "a.jpg", Density: 1}, {URL: "b.jpg", Density: 1}},
+		},
+		{
+			name:   "empty",
+			srcset: "",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSrcset(tt.srcset); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSrcset(%q) = %+v, want %+v", tt.srcset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickCandidate(t *testing.T) {
+	widths := parseSrcset("s.jpg 320w, m.jpg 640w, l.jpg 1280w")
+	densities := parseSrcset("1x.jpg, 2x.jpg 2x")
+
+	tests := []struct {
+		name       string
+		candidates []srcCandidate
+		slot       int
+		want       string
+	}{
+		{"smallest wide enough", widths, 400, "m.jpg"},
+		{"exact width", widths, 320, "s.jpg"},
+		{"largest when none is wide enough", widths, 2000, "l.jpg"},
+		{"1x always fits", densities, 800, "1x.jpg"},
+		{"none", nil, 100, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pickCandidate(tt.candidates, tt.slot); got != tt.want {
+				t.Errorf("pickCandidate(%d) = %q, want %q", tt.slot, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlotWidth(t *testing.T) {
+	tests := []struct {
+		sizes    string
+		viewport int
+		want     int
+	}{
+		{"100vw", 800, 800},
+		{"50vw", 800, 400},
+		{"300px", 800, 300},
+		{"20em", 800, 320},
+		{"(max-width: 600px) 100vw, 50vw", 500, 500},
+		{"(max-width: 600px) 100vw, 50vw", 1000, 500},
+		{"(min-width: 40em) 600px, 100vw", 700, 600},
+		{"(min-width: 40em) 600px, 100vw", 500, 500},
+		{"(min-width: 800px) and (max-width: 1000px) 10px, 20px", 900, 10},
+		{"(min-width: 800px) and (max-width: 1000px) 10px, 20px", 1100, 20},
+		{"(orientation: landscape) 30px, 20px", 800, 30},
+		{"calc(100vw - 2em), 200px", 800, 200},
+		{"wide", 800, 800},
+		{"", 800, 800},
+	}
+
+	for _, tt := range tests {
+		if got := slotWidth(tt.sizes, tt.viewport); got != tt.want {
+			t.Errorf("slotWidth(%q, %d) = %d, want %d", tt.sizes, tt.viewport, got, tt.want)
+		}
+	}
+}
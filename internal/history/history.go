@@ -0,0 +1,269 @@
+// Package history records the pages the user has visited in a JSON file.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"ruppi/pkg/fuzzy"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxEntries caps the history; the entries with the lowest frecency are
+// forgotten first.
+const maxEntries = 5000
+
+// Entry is a visited page.
+type Entry struct {
+	URL       string    `json:"url"`
+	Title     string    `json:"title"`
+	Visits    int       `json:"visits"`
+	LastVisit time.Time `json:"last_visit"`
+}
+
+// Store holds the history and the file it is saved to.
+type Store struct {
+	path    string
+	Entries []Entry `json:"entries"`
+
+	// changed is set when visits were recorded since the last save.
+	changed bool
+	// loadErr is why the file couldn't be loaded while it is still in
+	// place. The store isn't saved over it then, so it isn't lost.
+	loadErr error
+}
+
+// Load reads the history saved at path. A missing file gives an empty store
+// that will be created on the first save. A file that can't be decoded is
+// moved aside to path.bak and the store starts empty; one that can't be read
+// is left alone, and the store won't save over it.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		s.loadErr = fmt.Errorf("could not read history: %w", err)
+		return s, s.loadErr
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		// start over, keeping the file that couldn't be decoded aside
+		s.Entries = nil
+		backup := path + ".bak"
+		if renameErr := os.Rename(path, backup); renameErr != nil {
+			s.loadErr = fmt.Errorf("could not decode history: %w", err)
+			return s, s.loadErr
+		}
+		return s, fmt.Errorf("could not decode history, moved it to %s: %w", backup, err)
+	}
+	return s, nil
+}
+
+// Save writes the history back to its file.
+func (s *Store) Save() error {
+	if s.loadErr != nil {
+		return fmt.Errorf("not saving over the history that couldn't be loaded: %w", s.loadErr)
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("could not encode history: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("could not create history directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("could not write history: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.changed = false
+	return nil
+}
+
+// Flush saves the history if visits were recorded since it was last saved.
+func (s *Store) Flush() error {
+	if !s.changed {
+		return nil
+	}
+	return s.Save()
+}
+
+// Record adds a visit to url. Visits are only kept in memory until the next
+// Save or Flush, so navigating doesn't write the whole file each time.
+func (s *Store) Record(url, title string) {
+	now := time.Now()
+	s.changed = true
+
+	if i := s.index(url); i >= 0 {
+		s.Entries[i].Visits++
+		s.Entries[i].LastVisit = now
+		if title != "" {
+			s.Entries[i].Title = title
+		}
+	} else {
+		s.Entries = append(s.Entries, Entry{URL: url, Title: title, Visits: 1, LastVisit: now})
+		s.prune(now)
+	}
+}
+
+// Remove forgets url.
+func (s *Store) Remove(url string) error {
+	i := s.index(url)
+	if i < 0 {
+		return nil
+	}
+
+	s.Entries = append(s.Entries[:i], s.Entries[i+1:]...)
+	return s.Save()
+}
+
+// Clear forgets every visit.
+func (s *Store) Clear() error {
+	s.Entries = nil
+	return s.Save()
+}
+
+func (s *Store) index(url string) int {
+	for i, e := range s.Entries {
+		if e.URL == url {
+			return i
+		}
+	}
+	return -1
+}
+
+// prune drops the least useful entries once the history is over maxEntries.
+func (s *Store) prune(now time.Time) {
+	if len(s.Entries) <= maxEntries {
+		return
+	}
+
+	sort.SliceStable(s.Entries, func(i, j int) bool {
+		return s.Entries[i].Frecency(now) > s.Entries[j].Frecency(now)
+	})
+	s.Entries = s.Entries[:maxEntries]
+}
+
+// Frecency scores an entry by how often and how recently it was visited, the
+// way browsers rank their address bar suggestions.
+func (e Entry) Frecency(now time.Time) int {
+	age := now.Sub(e.LastVisit)
+
+	var weight int
+	switch {
+	case age < 4*24*time.Hour:
+		weight = 100
+	case age < 14*24*time.Hour:
+		weight = 70
+	case age < 31*24*time.Hour:
+		weight = 50
+	case age < 90*24*time.Hour:
+		weight = 30
+	default:
+		weight = 10
+	}
+	return e.Visits * weight
+}
+
+// Recent returns the entries, most recently visited first.
+func (s *Store) Recent() []Entry {
+	entries := append([]Entry(nil), s.Entries...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastVisit.After(entries[j].LastVisit)
+	})
+	return entries
+}
+
+// Search returns the entries fuzzy matching query on their title and URL.
+// The match score is weighed by frecency, so pages visited often and lately
+// come first.
+func (s *Store) Search(query string) []Entry {
+	items := make([]string, len(s.Entries))
+	for i, e := range s.Entries {
+		items[i] = e.Title + " " + e.URL
+	}
+
+	now := time.Now()
+	matches := fuzzy.Find(query, items)
+	sort.SliceStable(matches, func(i, j int) bool {
+		return rank(matches[i], s.Entries[matches[i].Index], now) > rank(matches[j], s.Entries[matches[j].Index], now)
+	})
+
+	results := make([]Entry, len(matches))
+	for i, m := range matches {
+		results[i] = s.Entries[m.Index]
+	}
+	return results
+}
+
+func rank(m fuzzy.Match, e Entry, now time.Time) int {
+	return m.Score * (e.Frecency(now) + 100)
+}
+
+// Completion is a URL written the way the user started typing it.
+type Completion struct {
+	Text string
+	URL  string
+}
+
+// Complete returns the URLs starting with prefix, best frecency first. The
+// scheme and a leading "www." may be left out of prefix, and each completion
+// is written the same way prefix was, so "go" completes to "go.dev/" rather
+// than "https://go.dev/".
+func (s *Store) Complete(prefix string, limit int) []Completion {
+	prefix = strings.ToLower(prefix)
+	if prefix == "" {
+		return nil
+	}
+
+	var completions []Completion
+	var scores []int
+	now := time.Now()
+	for _, e := range s.Entries {
+		for _, form := range urlForms(e.URL) {
+			if strings.HasPrefix(strings.ToLower(form), prefix) {
+				completions = append(completions, Completion{Text: form, URL: e.URL})
+				scores = append(scores, e.Frecency(now))
+				break
+			}
+		}
+	}
+
+	indices := make([]int, len(completions))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return scores[indices[i]] > scores[indices[j]]
+	})
+
+	results := make([]Completion, 0, min(limit, len(indices)))
+	for _, i := range indices[:min(limit, len(indices))] {
+		results = append(results, completions[i])
+	}
+	return results
+}
+
+// urlForms returns url as typed with and without its scheme and "www.".
+func urlForms(url string) []string {
+	forms := []string{url}
+	if _, rest, ok := strings.Cut(url, "://"); ok {
+		forms = append(forms, rest)
+		if bare, ok := strings.CutPrefix(rest, "www."); ok {
+			forms = append(forms, bare)
+		}
+	}
+	return forms
+}
@@ -0,0 +1,66 @@
+package history
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFlushSavesRecordedVisits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Record("https://go.dev/", "Go")
+	if _, err := os.Stat(path); err == nil {
+		t.Error("Record wrote the file")
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Entries) != 1 || loaded.Entries[0].URL != "https://go.dev/" {
+		t.Errorf("loaded %+v", loaded.Entries)
+	}
+}
+
+func TestLoadCorruptFileIsKept(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	corrupt := []byte(`{"entries": [{"url": "https://go.dev/"`)
+	if err := os.WriteFile(path, corrupt, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Load(path)
+	if err == nil {
+		t.Fatal("Load of a corrupt file didn't fail")
+	}
+	s.Record("https://example.com/", "Example")
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := os.ReadFile(path + ".bak")
+	if err != nil || !bytes.Equal(backup, corrupt) {
+		t.Errorf("corrupt file wasn't moved aside: %q, %v", backup, err)
+	}
+}
+
+func TestUnreadableFileIsNotOverwritten(t *testing.T) {
+	// a directory can't be read as a file
+	s, err := Load(t.TempDir())
+	if err == nil {
+		t.Fatal("Load of an unreadable file didn't fail")
+	}
+	s.Record("https://example.com/", "Example")
+	if err := s.Flush(); err == nil {
+		t.Error("saved over a file that couldn't be read")
+	}
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"bytes"
 	"io"
 	"ruppi/internal/dom"
 	"strings"
@@ -15,19 +16,20 @@ func Parse(r io.Reader) (dom.Node, string, error) {
 		return dom.Node{}, "", err
 	}
 
-	transformedNode, title := transform(doc)
+	transformedNode, title := transform(doc, false)
 
 	return transformedNode, title, nil
 }
 
-func transform(n *html.Node) (dom.Node, string) {
+// transform converts n to a dom.Node. White space outside of preformatted
+// elements, where preformatted is set, is collapsed into a single space; the
+// layout decides whether it shows.
+func transform(n *html.Node, preformatted bool) (dom.Node, string) {
 	var foundTitle string
 
 	if n.Type == html.TextNode {
-		trimmedData := strings.TrimSpace(n.Data)
-		if trimmedData == "" {
-
-			return dom.Node{Element: dom.ElementData{NodeType: dom.TEXT}, InnerText: ""}, ""
+		if strings.TrimSpace(n.Data) == "" && !preformatted {
+			return dom.Node{Element: dom.ElementData{NodeType: dom.TEXT}, InnerText: " "}, ""
 		}
 
 		return dom.Node{Element: dom.ElementData{NodeType: dom.TEXT}, InnerText: n.Data}, ""
@@ -40,8 +42,16 @@ func transform(n *html.Node) (dom.Node, string) {
 			return dom.Node{}, foundTitle
 		}
 
+		// Inline SVG is kept as markup and rasterised by the renderer
+		// like any other image.
+		if n.Type == html.ElementNode && n.Data == "svg" {
+			return transformSVG(n), ""
+		}
+
 		nodeType, ok := dom.TagToType[n.Data]
-		if !ok {
+		if n.Type == html.DocumentNode {
+			nodeType = dom.ROOT
+		} else if !ok {
 
 			if isBlockTag(n.Data) {
 				nodeType = dom.DIV
@@ -62,8 +72,9 @@ func transform(n *html.Node) (dom.Node, string) {
 			newNode.Element.Attrs[attr.Key] = attr.Val
 		}
 
+		preformatted = preformatted || isPreformattedTag(n.Data)
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			childNode, childTitle := transform(c)
+			childNode, childTitle := transform(c, preformatted)
 
 			if childTitle != "" && foundTitle == "" {
 				foundTitle = childTitle
@@ -83,9 +94,51 @@ func transform(n *html.Node) (dom.Node, string) {
 
 func isBlockTag(tag string) bool {
 	switch tag {
-	case "article", "section", "header", "footer", "aside", "figure", "figcaption", "main":
+	case "html", "body", "article", "section", "header", "footer", "aside", "main", "nav",
+		"form", "fieldset", "address", "table", "tr":
 		return true
 	default:
 		return false
 	}
 }
+
+func isPreformattedTag(tag string) bool {
+	switch tag {
+	case "pre", "textarea", "listing":
+		return true
+	default:
+		return false
+	}
+}
+
+func transformSVG(n *html.Node) dom.Node {
+	svgNode := dom.Node{
+		Element: dom.ElementData{
+			NodeType: dom.SVG,
+			Name:     n.Data,
+			Attrs:    make(map[string]string),
+		},
+	}
+
+	for _, attr := range n.Attr {
+		svgNode.Element.Attrs[attr.Key] = attr.Val
+	}
+
+	// Use the <title> child as alt text, the way screen readers do
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "title" && c.FirstChild != nil {
+			svgNode.Element.Attrs["alt"] = strings.TrimSpace(c.FirstChild.Data)
+			break
+		}
+	}
+	if label, ok := svgNode.Element.Attrs["aria-label"]; ok {
+		svgNode.Element.Attrs["alt"] = label
+	}
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, n); err == nil {
+		svgNode.InnerText = buf.String()
+	}
+
+	return svgNode
+}
@@ -0,0 +1,43 @@
+// Package version reports which build of Ruppi is running.
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Version is set at build time with
+//
+//	-ldflags "-X ruppi/internal/version.Version=v1.2.3"
+var Version = "dev"
+
+// Info describes the running binary.
+type Info struct {
+	Version   string
+	Revision  string
+	Modified  bool
+	GoVersion string
+	Platform  string
+}
+
+// Get returns the version along with the VCS revision Go embedded in the
+// binary, when it was built from a checkout.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Revision = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,85 @@
+// Package fuzzy ranks strings by how well they match a typed pattern.
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	matchScore       = 16
+	consecutiveBonus = 24
+	wordStartBonus   = 20
+	firstRuneBonus   = 32
+	gapPenalty       = 1
+)
+
+// Match is an item matched by Find.
+type Match struct {
+	// Index is the position of the item in the slice given to Find.
+	Index int
+	Score int
+}
+
+// Score reports whether every rune of pattern appears in text in order,
+// ignoring case, and how good the match is. Runs of consecutive runes and
+// matches at the start of words score higher, so "gm" ranks "Go Modules"
+// above "programming".
+func Score(pattern, text string) (int, bool) {
+	p := []rune(strings.ToLower(strings.TrimSpace(pattern)))
+	if len(p) == 0 {
+		return 0, true
+	}
+
+	t := []rune(text)
+	score, pi, last := 0, 0, -1
+	for ti := 0; ti < len(t) && pi < len(p); ti++ {
+		if unicode.ToLower(t[ti]) != p[pi] {
+			continue
+		}
+
+		score += matchScore
+		switch {
+		case ti == 0:
+			score += firstRuneBonus
+		case last == ti-1:
+			score += consecutiveBonus
+		case isWordStart(t, ti):
+			score += wordStartBonus
+		}
+		if last >= 0 {
+			score -= (ti - last - 1) * gapPenalty
+		}
+
+		last = ti
+		pi++
+	}
+
+	if pi < len(p) {
+		return 0, false
+	}
+	return score, true
+}
+
+func isWordStart(t []rune, i int) bool {
+	prev := t[i-1]
+	return !unicode.IsLetter(prev) && !unicode.IsDigit(prev) ||
+		unicode.IsLower(prev) && unicode.IsUpper(t[i])
+}
+
+// Find returns the items matching pattern, best match first. Items that
+// score the same keep their order.
+func Find(pattern string, items []string) []Match {
+	var matches []Match
+	for i, item := range items {
+		if score, ok := Score(pattern, item); ok {
+			matches = append(matches, Match{Index: i, Score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
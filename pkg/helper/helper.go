@@ -1,44 +1,49 @@
 package helper
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
 	"image"
+	"image/draw"
+	"image/gif"
+	"io"
 	// Import image format decoders
-	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
 	"math"
 	"math/rand"
 	"net/http"
 	"net/url"
+	"ruppi/pkg/svg"
 	"strings"
 	"time"
 
-	_ "golang.org/x/image/webp" // WebP support
+	"github.com/mattn/go-runewidth"
+	xdraw "golang.org/x/image/draw" // For high-quality image resizing
+	_ "golang.org/x/image/webp"     // WebP support
 )
 
-// Truncate adds 3(.) ie: ... at the end of the string
+// Truncate adds 3(.) ie: ... at the end of the string. maxLength counts
+// terminal columns, so wide characters such as CJK and emoji take two.
 func TruncateString(input string, maxLength int, truncate bool) string {
 	if maxLength < 0 {
 		return input
 	}
 
-	inputRune := []rune(input)
-	lengthOfInput := len(inputRune)
-
-	if lengthOfInput == maxLength {
-	}
-	if lengthOfInput <= maxLength {
-		return input + strings.Repeat(" ", maxLength-lengthOfInput)
+	width := runewidth.StringWidth(input)
+	if width <= maxLength {
+		return input + strings.Repeat(" ", maxLength-width)
 	}
 
 	end := ""
 	if truncate {
-		maxLength = maxLength - 3
 		end = "..."
 	}
 
-	return string(inputRune[:maxLength]) + end
+	// a wide character cut in half leaves a column to pad
+	truncated := runewidth.Truncate(input, maxLength, end)
+	return truncated + strings.Repeat(" ", max(maxLength-runewidth.StringWidth(truncated), 0))
 }
 
 // Enhanced color palettes for better visual consistency
@@ -123,7 +128,50 @@ var imageHTTPClient = &http.Client{
 	Timeout: 15 * time.Second,
 }
 
+// defaultFrameDelay is used for GIF frames that don't specify a usable delay,
+// matching what most browsers do.
+const defaultFrameDelay = 100 * time.Millisecond
+
+// Animation holds the decoded frames of an image. Still images have a single
+// frame and no delays.
+type Animation struct {
+	Frames []image.Image
+	Delays []time.Duration
+
+	// Data is the encoded image as it was downloaded and Format its type
+	// ("png", "gif", "svg", ...), kept so the original can be saved.
+	Data   []byte
+	Format string
+}
+
+// IsAnimated reports whether the image has more than one frame.
+func (a *Animation) IsAnimated() bool {
+	return len(a.Frames) > 1
+}
+
 func ImageFromURL(url string) (image.Image, error) {
+	anim, err := AnimationFromURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return anim.Frames[0], nil
+}
+
+// AnimationFromURL fetches and decodes every frame of the image at url.
+func AnimationFromURL(url string) (*Animation, error) {
+	if cached, ok := imageCache.Load(url); ok {
+		return cached, nil
+	}
+
+	if strings.HasPrefix(url, "data:") {
+		data, err := decodeDataURL(url)
+		if err != nil {
+			return nil, err
+		}
+		return DecodeAnimation(bytes.NewReader(data))
+	}
+
 	resp, err := imageHTTPClient.Get(url)
 	if err != nil {
 		return nil, err
@@ -134,10 +182,172 @@ func ImageFromURL(url string) (image.Image, error) {
 		return nil, fmt.Errorf("failed to fetch image: HTTP %d", resp.StatusCode)
 	}
 
-	img, _, err := image.Decode(resp.Body)
+	anim, err := DecodeAnimation(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	imageCache.Store(url, anim)
+	return anim, nil
+}
+
+// decodeDataURL returns the payload of a data: URL, e.g.
+// data:image/png;base64,iVBORw0...
+func decodeDataURL(dataURL string) ([]byte, error) {
+	header, payload, found := strings.Cut(strings.TrimPrefix(dataURL, "data:"), ",")
+	if !found {
+		return nil, fmt.Errorf("invalid data url")
+	}
+
+	if strings.HasSuffix(header, ";base64") {
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(payload))
+	}
+
+	unescaped, err := url.PathUnescape(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data url: %w", err)
+	}
+	return []byte(unescaped), nil
+}
+
+// DecodeAnimation decodes an image, keeping all frames if it is a GIF.
+func DecodeAnimation(r io.Reader) (*Animation, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		// SVG isn't an image.Decode format, so sniff for it separately
+		if svg.IsSVG(data) {
+			img, err := svg.Decode(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			return &Animation{Frames: []image.Image{img}, Data: data, Format: "svg"}, nil
+		}
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if format == "gif" {
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode image: %w", err)
+		}
+		anim := composeGIF(g)
+		anim.Data, anim.Format = data, format
+		return anim, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode image: %w", err)
 	}
 
-	return img, nil
+	return &Animation{Frames: []image.Image{img}, Data: data, Format: format}, nil
+}
+
+// composeGIF renders GIF frames onto a full canvas. GIF frames are often only
+// the changed region, so each one has to be drawn over the previous result
+// according to its disposal method.
+func composeGIF(g *gif.GIF) *Animation {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		bounds = g.Image[0].Bounds()
+	}
+
+	canvas := image.NewRGBA(bounds)
+	anim := &Animation{}
+
+	for i, frame := range g.Image {
+		var previous *image.RGBA
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			draw.Draw(previous, bounds, canvas, bounds.Min, draw.Src)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+		composed := image.NewRGBA(bounds)
+		draw.Draw(composed, bounds, canvas, bounds.Min, draw.Src)
+		anim.Frames = append(anim.Frames, composed)
+
+		delay := defaultFrameDelay
+		if i < len(g.Delay) && g.Delay[i] > 1 {
+			delay = time.Duration(g.Delay[i]) * 10 * time.Millisecond
+		}
+		anim.Delays = append(anim.Delays, delay)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	return anim
+}
+
+// ResizeImage scales an image to fit within maxWidth and maxHeight while preserving aspect ratio
+func ResizeImage(img image.Image, maxWidth, maxHeight int) image.Image {
+	bounds := img.Bounds()
+	origWidth := bounds.Dx()
+	origHeight := bounds.Dy()
+
+	// If image is already smaller than max dimensions, return as-is
+	if origWidth <= maxWidth && origHeight <= maxHeight {
+		return img
+	}
+
+	// Calculate scaling factor to fit within max dimensions
+	scaleW := float64(maxWidth) / float64(origWidth)
+	scaleH := float64(maxHeight) / float64(origHeight)
+	scale := scaleW
+	if scaleH < scaleW {
+		scale = scaleH
+	}
+
+	newWidth := int(float64(origWidth) * scale)
+	newHeight := int(float64(origHeight) * scale)
+
+	// Ensure minimum dimensions
+	if newWidth < 1 {
+		newWidth = 1
+	}
+	if newHeight < 1 {
+		newHeight = 1
+	}
+
+	// Create destination image
+	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+
+	// Use bilinear interpolation for good quality with reasonable speed
+	xdraw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, bounds, xdraw.Over, nil)
+
+	return dst
+}
+
+// ScaleRegion scales the region of img to fit within maxWidth and maxHeight
+// while preserving its aspect ratio. Unlike ResizeImage it also scales up, so
+// it can be used to zoom into part of an image.
+func ScaleRegion(img image.Image, region image.Rectangle, maxWidth, maxHeight int) image.Image {
+	region = region.Intersect(img.Bounds())
+	if region.Empty() || maxWidth <= 0 || maxHeight <= 0 {
+		return image.NewRGBA(image.Rect(0, 0, 1, 1))
+	}
+
+	scale := math.Min(float64(maxWidth)/float64(region.Dx()), float64(maxHeight)/float64(region.Dy()))
+	newWidth := max(1, int(float64(region.Dx())*scale))
+	newHeight := max(1, int(float64(region.Dy())*scale))
+
+	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	xdraw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, region, xdraw.Over, nil)
+
+	return dst
 }
@@ -0,0 +1,41 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/mattn/go-runewidth"
+)
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		width    int
+		truncate bool
+		want     string
+	}{
+		{"padded", "Go", 5, true, "Go   "},
+		{"exact", "Go", 2, true, "Go"},
+		{"cut with dots", "The Go Programming Language", 10, true, "The Go ..."},
+		{"cut without dots", "The Go Programming Language", 6, false, "The Go"},
+		{"negative width", "Go", -1, true, "Go"},
+		{"cjk padded", "日本語", 8, true, "日本語  "},
+		{"cjk cut with dots", "日本語テキスト", 9, true, "日本語..."},
+		{"cjk cut in half", "日本語", 5, false, "日本 "},
+		{"emoji padded", "🐦 Go", 7, true, "🐦 Go  "},
+		{"emoji cut", "🐦🐦🐦", 5, false, "🐦🐦 "},
+		{"right to left cut", "שלום עולם", 7, true, "שלום..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateString(tt.input, tt.width, tt.truncate)
+			if got != tt.want {
+				t.Errorf("TruncateString(%q, %d, %v) = %q, want %q", tt.input, tt.width, tt.truncate, got, tt.want)
+			}
+			if tt.width >= 0 && runewidth.StringWidth(got) != tt.width {
+				t.Errorf("TruncateString(%q, %d, %v) is %d columns wide", tt.input, tt.width, tt.truncate, runewidth.StringWidth(got))
+			}
+		})
+	}
+}
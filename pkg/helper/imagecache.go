@@ -0,0 +1,86 @@
+package helper
+
+import (
+	"container/list"
+	"sync"
+)
+
+// maxImageCacheSize is roughly how many bytes of images imageCache keeps.
+// Past it the least recently used images are dropped and fetched again when
+// they are needed.
+const maxImageCacheSize = 256 << 20
+
+// imageCache keeps decoded images by URL so re-rendering a page (e.g. on
+// resize) doesn't fetch every image again.
+var imageCache = newAnimationCache(maxImageCacheSize)
+
+// animationCache is a least recently used cache of images, bounded by the
+// memory their frames and data take up.
+type animationCache struct {
+	mu      sync.Mutex
+	maxSize int
+	size    int
+	order   *list.List // of *cachedAnimation, most recently used first
+	entries map[string]*list.Element
+}
+
+type cachedAnimation struct {
+	url  string
+	anim *Animation
+	size int
+}
+
+func newAnimationCache(maxSize int) *animationCache {
+	return &animationCache{maxSize: maxSize, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// Load returns the image cached for url.
+func (c *animationCache) Load(url string) (*Animation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[url]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*cachedAnimation).anim, true
+}
+
+// Store caches anim for url, dropping the least recently used images to make
+// room. An image bigger than the whole cache isn't kept.
+func (c *animationCache) Store(url string, anim *Animation) {
+	size := anim.size()
+	if size > c.maxSize {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[url]; ok {
+		c.remove(e)
+	}
+	c.entries[url] = c.order.PushFront(&cachedAnimation{url: url, anim: anim, size: size})
+	c.size += size
+
+	for c.size > c.maxSize {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *animationCache) remove(e *list.Element) {
+	cached := c.order.Remove(e).(*cachedAnimation)
+	delete(c.entries, cached.url)
+	c.size -= cached.size
+}
+
+// size is about how many bytes the image takes up in memory: four per pixel
+// of every frame, and its encoded data.
+func (a *Animation) size() int {
+	size := len(a.Data)
+	for _, frame := range a.Frames {
+		size += frame.Bounds().Dx() * frame.Bounds().Dy() * 4
+	}
+	return size
+}
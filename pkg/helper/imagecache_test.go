@@ -0,0 +1,38 @@
+package helper
+
+import (
+	"image"
+	"testing"
+)
+
+// still is an image of n bytes of pixels.
+func still(n int) *Animation {
+	return &Animation{Frames: []image.Image{image.NewRGBA(image.Rect(0, 0, n/4, 1))}}
+}
+
+func TestAnimationCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newAnimationCache(100)
+	c.Store("a", still(40))
+	c.Store("b", still(40))
+	c.Load("a")
+	c.Store("c", still(40))
+
+	for url, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := c.Load(url); ok != want {
+			t.Errorf("Load(%q) cached = %v, want %v", url, ok, want)
+		}
+	}
+	if c.size != 80 {
+		t.Errorf("size = %d, want 80", c.size)
+	}
+
+	c.Store("huge", still(200))
+	if _, ok := c.Load("huge"); ok {
+		t.Error("an image bigger than the cache was kept")
+	}
+
+	c.Store("a", still(60))
+	if c.size != 100 || c.order.Len() != 2 {
+		t.Errorf("replacing a: size %d with %d entries, want 100 with 2", c.size, c.order.Len())
+	}
+}
@@ -0,0 +1,153 @@
+package httpclient
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"net/url"
+	"ruppi/internal/config"
+	"ruppi/internal/dom"
+	"ruppi/internal/version"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:embed pages/*.html
+var pageFiles embed.FS
+
+// pages are the built-in pages, rendered through the same parser as any
+// other document so they pick up the configured styles.
+var pages = template.Must(template.New("").Funcs(template.FuncMap{"key": boundKeys}).ParseFS(pageFiles, "pages/*.html"))
+
+// KeyHelp is a key binding listed on the built-in pages.
+type KeyHelp struct {
+	Action      string
+	Keys        string
+	Description string
+}
+
+// helpKeys returns the active key bindings, once the browser has set it.
+var helpKeys func() []KeyHelp
+
+// SetHelpKeys makes about:help and the new tab page list the key bindings
+// returned by keys.
+func SetHelpKeys(keys func() []KeyHelp) {
+	helpKeys = keys
+}
+
+// boundKeys returns the keys bound to action, for the "key" page function.
+func boundKeys(action string) string {
+	if helpKeys == nil {
+		return ""
+	}
+	for _, k := range helpKeys() {
+		if k.Action == action {
+			return k.Keys
+		}
+	}
+	return ""
+}
+
+func listHelpKeys() []KeyHelp {
+	if helpKeys == nil {
+		return nil
+	}
+	return helpKeys()
+}
+
+func init() {
+	RegisterAboutPage("about", func(*url.URL) (string, error) {
+		return renderPage("about", AboutPages())
+	})
+	RegisterAboutPage("blank", func(*url.URL) (string, error) {
+		return renderPage("blank", nil)
+	})
+	RegisterAboutPage("newtab", func(*url.URL) (string, error) {
+		return renderPage("newtab", nil)
+	})
+	RegisterAboutPage("help", func(*url.URL) (string, error) {
+		return renderPage("help", map[string]any{
+			"Keys":          listHelpKeys(),
+			"Engines":       config.GetSearchEngines(),
+			"DefaultEngine": config.GetDefaultSearchEngine(),
+			"Pages":         AboutPages(),
+		})
+	})
+	RegisterAboutPage("config", func(*url.URL) (string, error) {
+		return renderPage("config", map[string]any{
+			"Path":          config.GetConfigPath(),
+			"Settings":      config.Settings(),
+			"Engines":       config.GetSearchEngines(),
+			"DefaultEngine": config.GetDefaultSearchEngine(),
+			"Tags":          config.StyledTags(),
+		})
+	})
+	RegisterAboutPage("version", func(*url.URL) (string, error) {
+		return renderPage("version", version.Get())
+	})
+}
+
+// renderPage executes the built-in page called name.
+func renderPage(name string, data any) (string, error) {
+	var sb strings.Builder
+	if err := pages.ExecuteTemplate(&sb, name+".html", data); err != nil {
+		return "", fmt.Errorf("Failed to render about:%s: %v", name, err)
+	}
+	return sb.String(), nil
+}
+
+// AboutHandler returns the HTML of an about: page. u holds the full address,
+// so handlers can read query parameters such as about:bookmarks?q=go.
+type AboutHandler func(u *url.URL) (string, error)
+
+var (
+	aboutMu    sync.RWMutex
+	aboutPages = map[string]AboutHandler{}
+)
+
+// RegisterAboutPage makes about:name render the page returned by handler.
+func RegisterAboutPage(name string, handler AboutHandler) {
+	aboutMu.Lock()
+	defer aboutMu.Unlock()
+	aboutPages[name] = handler
+}
+
+// AboutPages returns the names of the registered about: pages, sorted.
+func AboutPages() []string {
+	aboutMu.RLock()
+	defer aboutMu.RUnlock()
+
+	names := make([]string, 0, len(aboutPages))
+	for name := range aboutPages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsAboutURL reports whether address is an about: page.
+func IsAboutURL(address string) bool {
+	return strings.HasPrefix(address, "about:")
+}
+
+// aboutPage renders an about: address with its registered handler.
+func aboutPage(address string) (dom.Node, string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return dom.Node{}, "", fmt.Errorf("Invalid about page: %v", err)
+	}
+
+	aboutMu.RLock()
+	handler, ok := aboutPages[u.Opaque]
+	aboutMu.RUnlock()
+	if !ok {
+		return dom.Node{}, "", fmt.Errorf("Unknown about page: %s", address)
+	}
+
+	page, err := handler(u)
+	if err != nil {
+		return dom.Node{}, "", err
+	}
+	return parseDocument(strings.NewReader(page))
+}
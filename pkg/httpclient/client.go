@@ -2,65 +2,36 @@ package httpclient
 
 import (
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"ruppi/internal/config"
 	"ruppi/internal/dom"
 	"ruppi/internal/parser"
+	"strconv"
 	"strings"
 )
 
-const (
-	defaultPageString = `<title>Ruppi New Tab</title>
-<br>
-<h1>This is the default Page</h1>
-<hr>
-<p>What can I do here?</p>
-<ul>
-	<li>use <code>i</code> or click on the url bar.</li>
-    <li>use <code>h</code> to get help.</li>
-    <li>use <code>q</code> to quit ruppi.</li>
-</ul>
-<hr>
-`
-
-	errorPageString = `<title>Error</title>
-<br>
-<h1>There was an unexpected error</h1>
-<hr>
-`
-)
-
+// DefaultPage is the page shown in a new tab.
 func DefaultPage() (dom.Node, string, error) {
-	rootNode, title, err := parser.Parse(strings.NewReader(defaultPageString))
-	if err != nil {
-		return dom.Node{}, "", fmt.Errorf("Failed to parse HTML: %v", err)
-	}
-	return dom.Node{
-		Element: dom.ElementData{
-			NodeType: dom.ROOT,
-		},
-		Children: []dom.Node{
-			rootNode,
-		},
-	}, title, nil
+	return aboutPage("about:newtab")
 }
 
+// ErrorPage explains why a page could not be loaded.
 func ErrorPage(err error) (dom.Node, string, error) {
-	rootNode, title, err := parser.Parse(strings.NewReader(fmt.Sprintf("%s<div>Error: %s</div>", errorPageString, err.Error())))
-	if err != nil {
-		return dom.Node{}, "", fmt.Errorf("Failed to parse HTML: %v", err)
+	page, renderErr := renderPage("error", err.Error())
+	if renderErr != nil {
+		return dom.Node{}, "", renderErr
 	}
-	return dom.Node{
-		Element: dom.ElementData{
-			NodeType: dom.ROOT,
-		},
-		Children: []dom.Node{
-			rootNode,
-		},
-	}, title, nil
+	return parseDocument(strings.NewReader(page))
 }
 
 func GetUrlAsNode(url string) (dom.Node, string, error) {
+	if IsAboutURL(url) {
+		return aboutPage(url)
+	}
+
 	resp, err := http.Get(url)
 	if err != nil {
 		return dom.Node{}, "", fmt.Errorf("Failed to fetch URL: %v", err)
@@ -71,7 +42,12 @@ func GetUrlAsNode(url string) (dom.Node, string, error) {
 		return dom.Node{}, "", fmt.Errorf("Failed to get a valid response: %s", resp.Status)
 	}
 
-	rootNode, title, err := parser.Parse(resp.Body)
+	return parseDocument(resp.Body)
+}
+
+// parseDocument parses an HTML document and wraps it in a document root node.
+func parseDocument(r io.Reader) (dom.Node, string, error) {
+	rootNode, title, err := parser.Parse(r)
 	if err != nil {
 		return dom.Node{}, "", fmt.Errorf("Failed to parse HTML: %v", err)
 	}
@@ -87,15 +63,104 @@ func GetUrlAsNode(url string) (dom.Node, string, error) {
 	}, title, nil
 }
 
+// SearchURL returns the address searching for text. A leading search engine
+// keyword, as in "w go modules", picks that engine; otherwise the default one
+// is used.
 func SearchURL(text string) string {
-	// TODO: Add a real search engine url
-	return fmt.Sprintf("https://sairashgautam.com.np?search=%s", text)
+	engine := config.GetDefaultSearchEngine()
+	query := strings.TrimSpace(text)
+
+	if keyword, rest, ok := strings.Cut(query, " "); ok {
+		if e, found := config.GetSearchEngine(keyword); found && strings.TrimSpace(rest) != "" {
+			engine = e
+			query = strings.TrimSpace(rest)
+		}
+	}
+
+	return strings.ReplaceAll(engine.URL, "%s", url.QueryEscape(query))
 }
 
+// IsURL reports whether possibleUrl can be loaded as an address rather than
+// searched for.
 func IsURL(possibleUrl string) bool {
-	_, err := url.ParseRequestURI(possibleUrl)
-	if err != nil {
+	_, ok := NormalizeURL(possibleUrl)
+	return ok
+}
+
+// NormalizeURL turns what was typed in the URL bar into an absolute address.
+// Besides full http(s) and about: URLs it accepts bare hostnames such as
+// "example.com/docs", "localhost:8080" or "192.168.1.1", adding the scheme
+// they are most likely served over.
+func NormalizeURL(input string) (string, bool) {
+	input = strings.TrimSpace(input)
+	if input == "" || strings.ContainsAny(input, " \t\n") {
+		return "", false
+	}
+
+	if IsAboutURL(input) {
+		return input, true
+	}
+
+	if u, err := url.ParseRequestURI(input); err == nil {
+		switch strings.ToLower(u.Scheme) {
+		case "http", "https":
+			if u.Host != "" {
+				return input, true
+			}
+			return "", false
+		}
+	}
+
+	host := input
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host = host[:i]
+	}
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if _, err := strconv.Atoi(port); err != nil {
+			return "", false
+		}
+		host = h
+	}
+
+	switch {
+	case host == "localhost" || net.ParseIP(host) != nil:
+		return "http://" + input, true
+	case isHostname(host):
+		return "https://" + input, true
+	}
+	return "", false
+}
+
+// isHostname reports whether host looks like a domain name: dot separated
+// labels ending in an alphabetic top level domain.
+func isHostname(host string) bool {
+	labels := strings.Split(strings.ToLower(host), ".")
+	if len(labels) < 2 {
 		return false
 	}
+
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+
+	tld := labels[len(labels)-1]
+	if strings.HasPrefix(tld, "xn--") {
+		return true
+	}
+	if len(tld) < 2 {
+		return false
+	}
+	for _, c := range tld {
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
 	return true
 }
@@ -0,0 +1,59 @@
+// Package kitty encodes images for the kitty terminal graphics protocol.
+// https://sw.kovidgoyal.net/kitty/graphics-protocol/
+package kitty
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"ruppi/pkg/helper"
+	"strings"
+)
+
+const (
+	// chunkSize is the maximum payload size of a single escape sequence.
+	chunkSize = 4096
+
+	// ClearPlacements deletes every visible image placement, leaving the
+	// transmitted image data in the terminal.
+	ClearPlacements = "\x1b_Ga=d,d=a,q=2\x1b\\"
+)
+
+// Encode returns the escape sequences that transmit img and display it over
+// cols x rows cells at the cursor position. Sending another image with the
+// same id replaces the previous one, which is how animation frames are shown
+// in place. The cursor is not moved, so the surrounding text layout is kept.
+func Encode(img image.Image, id, cols, rows, maxWidth, maxHeight int) (string, error) {
+	if maxWidth > 0 && maxHeight > 0 {
+		img = helper.ResizeImage(img, maxWidth, maxHeight)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", fmt.Errorf("failed to encode image: %w", err)
+	}
+	payload := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	var sb strings.Builder
+	for start := 0; ; start += chunkSize {
+		end := min(start+chunkSize, len(payload))
+		more := 0
+		if end < len(payload) {
+			more = 1
+		}
+
+		sb.WriteString("\x1b_G")
+		if start == 0 {
+			fmt.Fprintf(&sb, "a=T,f=100,q=2,C=1,i=%d,p=1,c=%d,r=%d,", id, cols, rows)
+		}
+		fmt.Fprintf(&sb, "m=%d;%s\x1b\\", more, payload[start:end])
+
+		if end == len(payload) {
+			break
+		}
+	}
+
+	return sb.String(), nil
+}
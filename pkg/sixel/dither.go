@@ -0,0 +1,109 @@
+package sixel
+
+import (
+	"image"
+	"image/color"
+	"sync"
+)
+
+const (
+	// lutBits is how many bits per channel the palette lookup table keeps.
+	lutBits = 5
+	lutSize = 1 << (3 * lutBits)
+
+	// ditherSpread is how far (in 8 bit channel values) the Bayer matrix
+	// moves a pixel before it is matched to the palette.
+	ditherSpread = 32
+)
+
+// bayer4 is the 4x4 Bayer threshold matrix, scaled to -0.5..0.5.
+var bayer4 = func() [4][4]float64 {
+	m := [4][4]int{
+		{0, 8, 2, 10},
+		{12, 4, 14, 6},
+		{3, 11, 1, 9},
+		{15, 7, 13, 5},
+	}
+	var out [4][4]float64
+	for y := range m {
+		for x := range m[y] {
+			out[y][x] = (float64(m[y][x])+0.5)/16 - 0.5
+		}
+	}
+	return out
+}()
+
+// paletteLUT caches the nearest palette entry for colors quantized to
+// lutBits per channel, which is much faster than searching the palette for
+// every pixel like image/draw does.
+type paletteLUT struct {
+	palette color.Palette
+	entries []int16
+}
+
+func newPaletteLUT(palette color.Palette) *paletteLUT {
+	entries := make([]int16, lutSize)
+	for i := range entries {
+		entries[i] = -1
+	}
+	return &paletteLUT{palette: palette, entries: entries}
+}
+
+func (l *paletteLUT) index(r, g, b uint8) uint8 {
+	key := int(r>>(8-lutBits))<<(2*lutBits) | int(g>>(8-lutBits))<<lutBits | int(b>>(8-lutBits))
+	if idx := l.entries[key]; idx >= 0 {
+		return uint8(idx)
+	}
+
+	idx := l.palette.Index(color.RGBA{r, g, b, 0xff})
+	l.entries[key] = int16(idx)
+	return uint8(idx)
+}
+
+// orderedDither maps img onto the palette of dst, spreading the work over
+// workers goroutines. When dither is false the pixels are matched to the
+// nearest color without any pattern. Unlike Floyd–Steinberg no error is
+// carried between pixels, so rows can be processed independently.
+func orderedDither(dst *image.Paletted, img image.Image, workers int, dither bool) {
+	bounds := img.Bounds()
+	rows := make(chan int, bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		rows <- y
+	}
+	close(rows)
+
+	var wg sync.WaitGroup
+	for range max(1, workers) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// each worker has its own table, so no locking is needed
+			lut := newPaletteLUT(dst.Palette)
+			for y := range rows {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					r, g, b, _ := img.At(x, y).RGBA()
+					offset := 0.0
+					if dither {
+						offset = bayer4[y&3][x&3] * ditherSpread
+					}
+					dst.SetColorIndex(x, y, lut.index(
+						clampChannel(float64(r>>8)+offset),
+						clampChannel(float64(g>>8)+offset),
+						clampChannel(float64(b>>8)+offset),
+					))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func clampChannel(v float64) uint8 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
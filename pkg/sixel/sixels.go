@@ -11,10 +11,11 @@ import (
 	"image/draw"
 	"io"
 	"ruppi/pkg/helper"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/soniakeys/quant/median"
-	xdraw "golang.org/x/image/draw" // For high-quality image resizing
 )
 
 // Encoder encode image to sixel format
@@ -33,26 +34,21 @@ type Encoder struct {
 	// If the value is below 2 (e.g. the zero value), then 255 is used.
 	// A color is always reserved for alpha, so 2 colors give you 1 color.
 	Colors int
-}
 
-// EncodeFromURL fetches an image from URL and returns sixel-encoded string
-// maxWidth and maxHeight control the maximum dimensions (use 0 for defaults)
-func EncodeFromURL(url string, maxWidth, maxHeight int) (string, error) {
-	if maxWidth <= 0 {
-		maxWidth = 400
-	}
-	if maxHeight <= 0 {
-		maxHeight = 300
-	}
+	// Ordered, if true, dithers with a 4x4 Bayer matrix instead of
+	// Floyd–Steinberg. It is much faster and can run in parallel, at the
+	// cost of a visible pattern. It takes precedence over Dither.
+	Ordered bool
 
-	e := &Encoder{
-		w:      strings.Builder{},
-		Width:  maxWidth,
-		Height: maxHeight,
-		Colors: 255,
-	}
+	// Workers sets how many goroutines encode the image. Values below 2
+	// encode on the calling goroutine only.
+	Workers int
+}
 
-	if err := e.EncodeFromUrl(url); err != nil {
+// EncodeToString encodes img and returns the sixel data.
+func (e *Encoder) EncodeToString(img image.Image) (string, error) {
+	e.w.Reset()
+	if err := e.Encode(img); err != nil {
 		return "", err
 	}
 	return e.w.String(), nil
@@ -72,49 +68,10 @@ func (e *Encoder) EncodeFromUrl(url string) error {
 	return e.Encode(img)
 }
 
-// resizeImage scales an image to fit within maxWidth and maxHeight while preserving aspect ratio
-func resizeImage(img image.Image, maxWidth, maxHeight int) image.Image {
-	bounds := img.Bounds()
-	origWidth := bounds.Dx()
-	origHeight := bounds.Dy()
-
-	// If image is already smaller than max dimensions, return as-is
-	if origWidth <= maxWidth && origHeight <= maxHeight {
-		return img
-	}
-
-	// Calculate scaling factor to fit within max dimensions
-	scaleW := float64(maxWidth) / float64(origWidth)
-	scaleH := float64(maxHeight) / float64(origHeight)
-	scale := scaleW
-	if scaleH < scaleW {
-		scale = scaleH
-	}
-
-	newWidth := int(float64(origWidth) * scale)
-	newHeight := int(float64(origHeight) * scale)
-
-	// Ensure minimum dimensions
-	if newWidth < 1 {
-		newWidth = 1
-	}
-	if newHeight < 1 {
-		newHeight = 1
-	}
-
-	// Create destination image
-	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
-
-	// Use bilinear interpolation for good quality with reasonable speed
-	xdraw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, bounds, xdraw.Over, nil)
-
-	return dst
-}
-
 // Encode do encoding
 func (e *Encoder) Encode(img image.Image) error {
 	nc := e.Colors // (>= 2, 8bit, index 0 is reserved for transparent key color)
-	if nc < 2 {
+	if nc < 2 || nc > 256 {
 		nc = 255
 	}
 
@@ -128,7 +85,7 @@ func (e *Encoder) Encode(img image.Image) error {
 		if maxH <= 0 {
 			maxH = 600 // Default max height
 		}
-		img = resizeImage(img, maxW, maxH)
+		img = helper.ResizeImage(img, maxW, maxH)
 	}
 
 	width, height := img.Bounds().Dx(), img.Bounds().Dy()
@@ -140,23 +97,7 @@ func (e *Encoder) Encode(img image.Image) error {
 	e.Width = width
 	e.Height = height
 
-	var paletted *image.Paletted
-
-	// fast path for paletted images
-	if p, ok := img.(*image.Paletted); ok && len(p.Palette) < int(nc) {
-		paletted = p
-	} else {
-		// make adaptive palette using median cut alogrithm
-		q := median.Quantizer(nc - 1)
-		paletted = q.Paletted(img)
-
-		if e.Dither {
-			// copy source image to new image with applying floyd-stenberg dithering
-			draw.FloydSteinberg.Draw(paletted, img.Bounds(), img, image.Point{})
-		} else {
-			draw.Draw(paletted, img.Bounds(), img, image.Point{}, draw.Over)
-		}
-	}
+	paletted := e.palettedImage(img, nc)
 
 	// DECSIXEL Introducer(\033P0;0;8q) + DECGRA ("1;1;W;H): Set Raster Attributes
 
@@ -177,168 +118,174 @@ func (e *Encoder) Encode(img image.Image) error {
 		// DECGCI (#): Graphics Color Introducer
 	}
 
-	buf := make([]byte, width*nc)
-	cset := make([]bool, nc)
-	ch0 := specialChNr
-	for z := 0; z < (height+5)/6; z++ {
-		// DECGNL (-): Graphics Next Line
-		if z > 0 {
-			if err := e.w.WriteByte(0x2d); err != nil {
-				return err
+	// Bands of six pixel rows don't depend on each other, so they can be
+	// encoded concurrently and joined with DECGNL (-): Graphics Next Line
+	bands := make([]string, (height+5)/6)
+	workers := max(1, min(e.Workers, len(bands)))
+	next := make(chan int, len(bands))
+	for z := range bands {
+		next <- z
+	}
+	close(next)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			enc := newBandEncoder(img, paletted, nc)
+			for z := range next {
+				bands[z] = enc.encode(z)
 			}
+		}()
+	}
+	wg.Wait()
+
+	e.w.WriteString(strings.Join(bands, "-"))
+
+	// string terminator(ST)
+	if _, err := e.w.Write([]byte{0x1b, 0x5c}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// String returns everything encoded so far.
+func (e *Encoder) String() string {
+	return e.w.String()
+}
+
+// palettedImage maps img onto an adaptive palette of at most nc-1 colors.
+func (e *Encoder) palettedImage(img image.Image, nc int) *image.Paletted {
+	// fast path for paletted images
+	if p, ok := img.(*image.Paletted); ok && len(p.Palette) < nc {
+		return p
+	}
+
+	// make adaptive palette using median cut alogrithm
+	q := median.Quantizer(nc - 1)
+	paletted := q.Paletted(img)
+
+	switch {
+	case e.Ordered:
+		orderedDither(paletted, img, max(1, e.Workers), true)
+	case e.Dither:
+		// copy source image to new image with applying floyd-stenberg dithering
+		draw.FloydSteinberg.Draw(paletted, img.Bounds(), img, img.Bounds().Min)
+	default:
+		draw.Draw(paletted, img.Bounds(), img, img.Bounds().Min, draw.Over)
+	}
+
+	return paletted
+}
+
+// bandEncoder encodes six pixel row bands of an image. It holds the scratch
+// buffers for one worker.
+type bandEncoder struct {
+	img      image.Image
+	paletted *image.Paletted
+	opaque   bool
+	nc       int
+	buf      []byte
+	cset     []bool
+}
+
+func newBandEncoder(img image.Image, paletted *image.Paletted, nc int) *bandEncoder {
+	opaque := false
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		opaque = o.Opaque()
+	}
+
+	return &bandEncoder{
+		img:      img,
+		paletted: paletted,
+		opaque:   opaque,
+		nc:       nc,
+		buf:      make([]byte, img.Bounds().Dx()*nc),
+		cset:     make([]bool, nc),
+	}
+}
+
+// encode returns the sixel data of band z, covering pixel rows 6z to 6z+5.
+func (be *bandEncoder) encode(z int) string {
+	var w strings.Builder
+	bounds := be.img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	buf, cset := be.buf, be.cset
+	for n := range cset {
+		cset[n] = true
+	}
+
+	for p := range 6 {
+		y := z*6 + p
+		if y >= height {
+			break
 		}
-		for p := range 6 {
-			y := z*6 + p
-			for x := 0; x < width; x++ {
-				_, _, _, alpha := img.At(x, y).RGBA()
-				if alpha != 0 {
-					idx := paletted.ColorIndexAt(x, y) + 1
-					cset[idx] = false // mark as used
-					buf[width*int(idx)+x] |= 1 << uint(p)
+		for x := 0; x < width; x++ {
+			if !be.opaque {
+				_, _, _, alpha := be.img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				if alpha == 0 {
+					continue
 				}
 			}
+			idx := int(be.paletted.ColorIndexAt(bounds.Min.X+x, bounds.Min.Y+y)) + 1
+			cset[idx] = false // mark as used
+			buf[width*idx+x] |= 1 << uint(p)
 		}
-		for n := 1; n < nc; n++ {
-			if cset[n] {
-				continue
-			}
-			cset[n] = true
-			// DECGCR ($): Graphics Carriage Return
-			if ch0 == specialChCr {
-				if err := e.w.WriteByte(0x24); err != nil {
-					return err
-				}
-			}
-			// select color (#%d)
-			if n >= 100 {
-				digit1 := n / 100
-				digit2 := (n - digit1*100) / 10
-				digit3 := n % 10
-				c1 := byte(0x30 + digit1)
-				c2 := byte(0x30 + digit2)
-				c3 := byte(0x30 + digit3)
-				if _, err := e.w.Write([]byte{0x23, c1, c2, c3}); err != nil {
-					return nil
-				}
-			} else if n >= 10 {
-				c1 := byte(0x30 + n/10)
-				c2 := byte(0x30 + n%10)
-				if _, err := e.w.Write([]byte{0x23, c1, c2}); err != nil {
-					return err
-				}
-			} else {
-				if _, err := e.w.Write([]byte{0x23, byte(0x30 + n)}); err != nil {
-					return err
-				}
-			}
-			cnt := 0
-			for x := 0; x < width; x++ {
-				// make sixel character from 6 pixels
-				ch := buf[width*n+x]
-				buf[width*n+x] = 0
-				if ch0 < 0x40 && ch != ch0 {
-					// output sixel character
-					s := 63 + ch0
-					for ; cnt > 255; cnt -= 255 {
-						if _, err := e.w.Write([]byte{0x21, 0x32, 0x35, 0x35, s}); err != nil {
-							return err
-						}
-					}
-					if cnt == 1 {
-						if err := e.w.WriteByte(s); err != nil {
-							return err
-						}
-					} else if cnt == 2 {
-						if _, err := e.w.Write([]byte{s, s}); err != nil {
-							return err
-						}
-					} else if cnt == 3 {
-						if _, err := e.w.Write([]byte{s, s, s}); err != nil {
-							return err
-						}
-					} else if cnt >= 100 {
-						digit1 := cnt / 100
-						digit2 := (cnt - digit1*100) / 10
-						digit3 := cnt % 10
-						c1 := byte(0x30 + digit1)
-						c2 := byte(0x30 + digit2)
-						c3 := byte(0x30 + digit3)
-						// DECGRI (!): - Graphics Repeat Introducer
-						if _, err := e.w.Write([]byte{0x21, c1, c2, c3, s}); err != nil {
-							return err
-						}
-					} else if cnt >= 10 {
-						c1 := byte(0x30 + cnt/10)
-						c2 := byte(0x30 + cnt%10)
-						// DECGRI (!): - Graphics Repeat Introducer
-						if _, err := e.w.Write([]byte{0x21, c1, c2, s}); err != nil {
-							return err
-						}
-					} else if cnt > 0 {
-						// DECGRI (!): - Graphics Repeat Introducer
-						if _, err := e.w.Write([]byte{0x21, byte(0x30 + cnt), s}); err != nil {
-							return err
-						}
-					}
-					cnt = 0
-				}
-				ch0 = ch
-				cnt++
-			}
-			if ch0 != 0 {
-				// output sixel character
-				s := 63 + ch0
-				for ; cnt > 255; cnt -= 255 {
-					if _, err := e.w.Write([]byte{0x21, 0x32, 0x35, 0x35, s}); err != nil {
-						return err
-					}
-				}
-				if cnt == 1 {
-					if err := e.w.WriteByte(s); err != nil {
-						return err
-					}
-				} else if cnt == 2 {
-					if _, err := e.w.Write([]byte{s, s}); err != nil {
-						return err
-					}
-				} else if cnt == 3 {
-					if _, err := e.w.Write([]byte{s, s, s}); err != nil {
-						return err
-					}
-				} else if cnt >= 100 {
-					digit1 := cnt / 100
-					digit2 := (cnt - digit1*100) / 10
-					digit3 := cnt % 10
-					c1 := byte(0x30 + digit1)
-					c2 := byte(0x30 + digit2)
-					c3 := byte(0x30 + digit3)
-					// DECGRI (!): - Graphics Repeat Introducer
-					if _, err := e.w.Write([]byte{0x21, c1, c2, c3, s}); err != nil {
-						return err
-					}
-				} else if cnt >= 10 {
-					c1 := byte(0x30 + cnt/10)
-					c2 := byte(0x30 + cnt%10)
-					// DECGRI (!): - Graphics Repeat Introducer
-					if _, err := e.w.Write([]byte{0x21, c1, c2, s}); err != nil {
-						return err
-					}
-				} else if cnt > 0 {
-					// DECGRI (!): - Graphics Repeat Introducer
-					if _, err := e.w.Write([]byte{0x21, byte(0x30 + cnt), s}); err != nil {
-						return err
-					}
-				}
+	}
+
+	ch0 := specialChNr
+	for n := 1; n < be.nc; n++ {
+		if cset[n] {
+			continue
+		}
+		// DECGCR ($): Graphics Carriage Return
+		if ch0 == specialChCr {
+			w.WriteByte(0x24)
+		}
+		// select color (#%d)
+		w.WriteByte(0x23)
+		w.WriteString(strconv.Itoa(n))
+
+		cnt := 0
+		for x := 0; x < width; x++ {
+			// make sixel character from 6 pixels
+			ch := buf[width*n+x]
+			buf[width*n+x] = 0
+			if ch0 < 0x40 && ch != ch0 {
+				writeRepeat(&w, cnt, 63+ch0)
+				cnt = 0
 			}
-			ch0 = specialChCr
+			ch0 = ch
+			cnt++
 		}
-	}
-	// string terminator(ST)
-	if _, err := e.w.Write([]byte{0x1b, 0x5c}); err != nil {
-		return err
+		if ch0 != 0 {
+			writeRepeat(&w, cnt, 63+ch0)
+		}
+		ch0 = specialChCr
 	}
 
-	return nil
+	return w.String()
+}
+
+// writeRepeat writes the sixel character s cnt times, using DECGRI (!):
+// Graphics Repeat Introducer when that is shorter.
+func writeRepeat(w *strings.Builder, cnt int, s byte) {
+	for ; cnt > 255; cnt -= 255 {
+		w.Write([]byte{0x21, 0x32, 0x35, 0x35, s})
+	}
+	switch {
+	case cnt <= 0:
+	case cnt <= 3:
+		for range cnt {
+			w.WriteByte(s)
+		}
+	default:
+		w.WriteByte(0x21)
+		w.WriteString(strconv.Itoa(cnt))
+		w.WriteByte(s)
+	}
 }
 
 // Decoder decode sixel format into image
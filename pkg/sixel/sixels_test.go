@@ -0,0 +1,75 @@
+package sixel
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// photo returns a w x h image with smooth gradients and noise, which is
+// what dithering and palette size make a difference on.
+func photo(w, h int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	seed := uint32(1)
+	for y := range h {
+		for x := range w {
+			seed = seed*1664525 + 1013904223
+			noise := uint8(seed >> 28)
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(x*255/w) ^ noise,
+				G: uint8(y*255/h) + noise,
+				B: uint8((x+y)*255/(w+h)) - noise,
+				A: 255,
+			})
+		}
+	}
+	return img
+}
+
+func BenchmarkEncode(b *testing.B) {
+	img := photo(400, 300)
+	cpus := runtime.NumCPU()
+	settings := []struct {
+		name    string
+		encoder Encoder
+	}{
+		{"none/255", Encoder{Colors: 255}},
+		{fmt.Sprintf("none/255/x%d", cpus), Encoder{Colors: 255, Workers: cpus}},
+		{fmt.Sprintf("ordered/255/x%d", cpus), Encoder{Colors: 255, Ordered: true, Workers: cpus}},
+		{"floyd-steinberg/255", Encoder{Colors: 255, Dither: true}},
+		{fmt.Sprintf("ordered/16/x%d", cpus), Encoder{Colors: 16, Ordered: true, Workers: cpus}},
+		{"floyd-steinberg/16", Encoder{Colors: 16, Dither: true}},
+	}
+
+	for _, s := range settings {
+		b.Run(s.name, func(b *testing.B) {
+			for b.Loop() {
+				enc := s.encoder
+				if _, err := enc.EncodeToString(img); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	img := photo(40, 30)
+	for _, enc := range []*Encoder{{}, {Ordered: true, Workers: 4}, {Dither: true}, {Colors: 16}} {
+		data, err := enc.EncodeToString(img)
+		if err != nil {
+			t.Fatalf("%+v: %v", enc, err)
+		}
+
+		var decoded image.Image
+		if err := NewDecoder(strings.NewReader(data)).Decode(&decoded); err != nil {
+			t.Fatalf("%+v: decoding: %v", enc, err)
+		}
+		if got := decoded.Bounds().Size(); got != img.Bounds().Size() {
+			t.Errorf("%+v: decoded size %v, want %v", enc, got, img.Bounds().Size())
+		}
+	}
+}
@@ -47,6 +47,22 @@ func LogoStyle() lipgloss.Style {
 		Foreground(lipgloss.Color(theme.TabActiveTextColor))
 }
 
+// OmniboxStyle is a row of the URL bar suggestions dropdown.
+func OmniboxStyle() lipgloss.Style {
+	theme := config.GetTheme()
+	return lipgloss.NewStyle().
+		Background(lipgloss.Color(theme.StatusBarColor)).
+		Foreground(lipgloss.Color(theme.BrowserForeground))
+}
+
+// OmniboxSelectedStyle is the highlighted row of the suggestions dropdown.
+func OmniboxSelectedStyle() lipgloss.Style {
+	theme := config.GetTheme()
+	return lipgloss.NewStyle().
+		Background(lipgloss.Color(theme.TabActiveColor)).
+		Foreground(lipgloss.Color(theme.TabActiveTextColor))
+}
+
 func InspectorStyle() lipgloss.Style {
 	theme := config.GetTheme()
 	return lipgloss.NewStyle().
@@ -0,0 +1,111 @@
+// Package svg rasterises SVG documents so they can be shown by the same
+// image backends as bitmap formats.
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+const (
+	// defaultWidth and defaultHeight are the CSS default object size, used
+	// when the document doesn't say how big it is.
+	defaultWidth  = 300
+	defaultHeight = 150
+
+	// maxSize keeps huge or bogus width/height values from allocating
+	// enormous canvases.
+	maxSize = 2048
+
+	// sniffLength is how much of a document is checked by IsSVG.
+	sniffLength = 1024
+)
+
+var (
+	svgTagPattern  = regexp.MustCompile(`(?is)<svg[\s>]`)
+	svgRootPattern = regexp.MustCompile(`(?is)<svg[^>]*>`)
+	svgSizePattern = regexp.MustCompile(`(?is)\s(width|height)\s*=\s*["']?\s*([0-9.]+)\s*(px)?["'\s/>]`)
+)
+
+// IsSVG reports whether data looks like an SVG document.
+func IsSVG(data []byte) bool {
+	head := data[:min(len(data), sniffLength)]
+	return svgTagPattern.Match(head)
+}
+
+// Decode rasterises the SVG document read from r at its intrinsic size.
+func Decode(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read svg: %w", err)
+	}
+
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse svg: %w", err)
+	}
+
+	width, height := intrinsicSize(data, icon)
+	if icon.ViewBox.W <= 0 || icon.ViewBox.H <= 0 {
+		icon.ViewBox.W = float64(width)
+		icon.ViewBox.H = float64(height)
+	}
+	icon.SetTarget(0, 0, float64(width), float64(height))
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	scanner := rasterx.NewScannerGV(width, height, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(width, height, scanner), 1)
+
+	return img, nil
+}
+
+// intrinsicSize works out the pixel size of the document from the width and
+// height attributes of the root element, falling back to the viewBox aspect
+// ratio and then to the CSS defaults.
+func intrinsicSize(data []byte, icon *oksvg.SvgIcon) (int, int) {
+	var width, height float64
+	root := svgRootPattern.Find(data)
+	for _, match := range svgSizePattern.FindAllSubmatch(root, -1) {
+		v, err := strconv.ParseFloat(string(match[2]), 64)
+		if err != nil {
+			continue
+		}
+		if strings.EqualFold(string(match[1]), "width") {
+			width = v
+		} else {
+			height = v
+		}
+	}
+
+	vbW, vbH := icon.ViewBox.W, icon.ViewBox.H
+	switch {
+	case width > 0 && height > 0:
+	case width > 0 && vbW > 0 && vbH > 0:
+		height = width * vbH / vbW
+	case height > 0 && vbW > 0 && vbH > 0:
+		width = height * vbW / vbH
+	case vbW > 0 && vbH > 0:
+		width, height = vbW, vbH
+	default:
+		width, height = defaultWidth, defaultHeight
+	}
+
+	return clampSize(width, height)
+}
+
+// clampSize scales a size over maxSize down to fit it, keeping its aspect
+// ratio, and rounds it to whole pixels.
+func clampSize(width, height float64) (int, int) {
+	if largest := max(width, height); largest > maxSize {
+		width, height = width*maxSize/largest, height*maxSize/largest
+	}
+	return max(1, int(width+0.5)), max(1, int(height+0.5))
+}
@@ -0,0 +1,24 @@
+package svg
+
+import "testing"
+
+func TestClampSize(t *testing.T) {
+	tests := []struct {
+		width, height float64
+		wantW, wantH  int
+	}{
+		{300, 150, 300, 150},
+		{4096, 1024, 2048, 512},
+		{1000, 8192, 250, 2048},
+		{100000, 10, 2048, 1},
+		{0.2, 0.4, 1, 1},
+		{-5, -5, 1, 1},
+	}
+
+	for _, tt := range tests {
+		w, h := clampSize(tt.width, tt.height)
+		if w != tt.wantW || h != tt.wantH {
+			t.Errorf("clampSize(%v, %v) = %d, %d, want %d, %d", tt.width, tt.height, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}